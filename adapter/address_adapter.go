@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,7 +11,7 @@ import (
 )
 
 type AddressAdapterInterface interface {
-	GetAddress(meterID int) (*Address, error)
+	GetAddress(ctx context.Context, meterID int) (*Address, error)
 }
 
 type Address struct {
@@ -29,10 +30,17 @@ func NewAddressAdapter() *AddressAdapter {
 	return &AddressAdapter{}
 }
 
-func callAddressService(meterID int) (Address, error) {
+const retryDelay = 2 * time.Second
+
+func callAddressService(ctx context.Context, meterID int) (Address, error) {
 	url := fmt.Sprintf("http://localhost:8082/address/%d", meterID)
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Address{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return Address{}, err
 	}
@@ -55,16 +63,27 @@ func callAddressService(meterID int) (Address, error) {
 	return Address{}, errors.New("failed to fetch address")
 }
 
-func (a *AddressAdapter) GetAddress(meterID int) (*Address, error) {
+func (a *AddressAdapter) GetAddress(ctx context.Context, meterID int) (*Address, error) {
 	var address Address
 	var err error
 
 	for attempts := 0; attempts < 2; attempts++ {
-		address, err = callAddressService(meterID)
+		if attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryDelay):
+			}
+		}
+
+		address, err = callAddressService(ctx, meterID)
 		if err == nil {
 			return &address, nil
 		}
-		time.Sleep(2 * time.Second)
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
 
 	if err != nil {
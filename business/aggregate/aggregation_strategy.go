@@ -1,7 +1,120 @@
 package aggregate
 
-import "github.com/SaidHernandez/bia-comsumtion/business/model"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+)
+
+// AggregationStrategy define cómo se divide el tiempo en periodos. Los límites
+// se calculan en la zona horaria de la fecha recibida.
 type AggregationStrategy interface {
-	Aggregate(consumptions []model.Consumption) map[string]model.AggregatedConsumption
+	// Truncate devuelve el inicio del periodo que contiene la fecha.
+	Truncate(date time.Time) time.Time
+	// Next devuelve el inicio del periodo siguiente al que empieza en start.
+	Next(start time.Time) time.Time
+	// Label devuelve la etiqueta del periodo que empieza en start.
+	Label(start time.Time) string
+}
+
+// Unidades de calendario con que la base de datos puede truncar fechas.
+const (
+	UnitHour  = "hour"
+	UnitDay   = "day"
+	UnitMonth = "month"
+	UnitYear  = "year"
+)
+
+// CalendarUnit lo implementan las estrategias cuyos periodos coinciden con una
+// unidad de calendario, lo que permite agrupar las lecturas en la base de datos.
+type CalendarUnit interface {
+	// Unit devuelve la unidad de los periodos y si la estrategia corresponde a una.
+	Unit() (string, bool)
+}
+
+// Aggregate agrupa las lecturas por periodo según la hora local de loc y las
+// devuelve ordenadas cronológicamente.
+func Aggregate(strategy AggregationStrategy, consumptions []model.Consumption, loc *time.Location) []model.AggregatedConsumption {
+	aggregation := make(map[int64]model.AggregatedConsumption)
+
+	for _, consumption := range consumptions {
+		start := strategy.Truncate(consumption.Date.In(loc))
+		key := start.UnixNano()
+
+		if _, exists := aggregation[key]; !exists {
+			aggregation[key] = model.AggregatedConsumption{
+				Start:              start,
+				Period:             strategy.Label(start),
+				ActiveEnergy:       []float64{},
+				ReactiveInductive:  []float64{},
+				ReactiveCapacitive: []float64{},
+				ExportedEnergy:     []float64{},
+			}
+		}
+
+		aggData := aggregation[key]
+
+		aggData.ActiveEnergy = append(aggData.ActiveEnergy, consumption.ActiveEnergy)
+		aggData.ReactiveInductive = append(aggData.ReactiveInductive, consumption.ReactiveInductive)
+		aggData.ReactiveCapacitive = append(aggData.ReactiveCapacitive, consumption.ReactiveCapacitive)
+		aggData.ExportedEnergy = append(aggData.ExportedEnergy, consumption.ExportedEnergy)
+
+		aggregation[key] = aggData
+	}
+
+	series := make([]model.AggregatedConsumption, 0, len(aggregation))
+	for _, aggData := range aggregation {
+		series = append(series, aggData)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Start.Before(series[j].Start)
+	})
+
+	return series
+}
+
+// ErrTooManyPeriods indica que el rango tiene más periodos que el máximo permitido.
+var ErrTooManyPeriods = errors.New("too many periods")
+
+// Periods devuelve el inicio de cada periodo que se solapa con el rango [from, to).
+// Si limit es mayor que cero y el rango tiene más periodos, se detiene sin
+// generarlos todos y devuelve ErrTooManyPeriods.
+func Periods(strategy AggregationStrategy, from, to time.Time, limit int) ([]time.Time, error) {
+	var periods []time.Time
+
+	for start := strategy.Truncate(from); start.Before(to); {
+		if limit > 0 && len(periods) == limit {
+			return nil, fmt.Errorf("%w: the range has more than %d periods", ErrTooManyPeriods, limit)
+		}
+		periods = append(periods, start)
+
+		next := strategy.Next(start)
+		if !next.After(start) {
+			break
+		}
+		start = next
+	}
+
+	return periods, nil
+}
+
+// Align ubica los periodos agregados sobre un eje común. Las posiciones sin
+// lecturas quedan en nil y los periodos fuera del eje se descartan.
+func Align(series []model.AggregatedConsumption, periods []time.Time) []*model.AggregatedConsumption {
+	positions := make(map[int64]int, len(periods))
+	for i, start := range periods {
+		positions[start.UnixNano()] = i
+	}
+
+	aligned := make([]*model.AggregatedConsumption, len(periods))
+	for i := range series {
+		if position, exists := positions[series[i].Start.UnixNano()]; exists {
+			aligned[position] = &series[i]
+		}
+	}
+
+	return aligned
 }
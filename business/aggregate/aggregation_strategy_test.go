@@ -0,0 +1,107 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriods(t *testing.T) {
+	from := time.Date(2023, 7, 4, 10, 20, 0, 0, time.UTC)
+	to := time.Date(2023, 7, 4, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		limit           int
+		expectedPeriods []time.Time
+		expectedError   string
+	}{
+		{
+			name:  "Periods overlapping the range",
+			limit: 0,
+			expectedPeriods: []time.Time{
+				time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC),
+				time.Date(2023, 7, 4, 11, 0, 0, 0, time.UTC),
+				time.Date(2023, 7, 4, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:            "Limit equal to the number of periods",
+			limit:           3,
+			expectedPeriods: []time.Time{time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC), time.Date(2023, 7, 4, 11, 0, 0, 0, time.UTC), time.Date(2023, 7, 4, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:          "Limit exceeded",
+			limit:         2,
+			expectedError: "too many periods: the range has more than 2 periods",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods, err := Periods(&HourlyAggregationStrategy{}, from, to, tt.limit)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.ErrorIs(t, err, ErrTooManyPeriods)
+				assert.Nil(t, periods)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedPeriods, periods)
+			}
+		})
+	}
+}
+
+func TestIntervalAggregationStrategy_DST(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	assert.NoError(t, err)
+	strategy := &IntervalAggregationStrategy{Step: 6 * time.Hour}
+
+	tests := []struct {
+		name            string
+		from            time.Time
+		to              time.Time
+		expectedPeriods []string
+	}{
+		{
+			name: "Day of 23 hours",
+			from: time.Date(2023, 3, 26, 0, 0, 0, 0, madrid),
+			to:   time.Date(2023, 3, 27, 0, 0, 0, 0, madrid),
+			expectedPeriods: []string{
+				"2023-03-26T00:00:00+01:00", "2023-03-26T06:00:00+02:00",
+				"2023-03-26T12:00:00+02:00", "2023-03-26T18:00:00+02:00",
+			},
+		},
+		{
+			name: "Day of 25 hours",
+			from: time.Date(2023, 10, 29, 0, 0, 0, 0, madrid),
+			to:   time.Date(2023, 10, 30, 0, 0, 0, 0, madrid),
+			expectedPeriods: []string{
+				"2023-10-29T00:00:00+02:00", "2023-10-29T06:00:00+01:00",
+				"2023-10-29T12:00:00+01:00", "2023-10-29T18:00:00+01:00",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods, err := Periods(strategy, tt.from, tt.to, 0)
+			assert.NoError(t, err)
+			starts := make([]string, len(periods))
+			for i, start := range periods {
+				starts[i] = start.Format(time.RFC3339)
+			}
+			assert.Equal(t, tt.expectedPeriods, starts)
+
+			// Cada lectura horaria del día cae en un periodo del eje.
+			positions := make(map[int64]bool, len(periods))
+			for _, start := range periods {
+				positions[start.UnixNano()] = true
+			}
+			for date := tt.from; date.Before(tt.to); date = date.Add(time.Hour) {
+				assert.True(t, positions[strategy.Truncate(date).UnixNano()], "reading at %s", date)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package aggregate
+
+import (
+	"math"
+	"sort"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+)
+
+// resetRatio es la caída mínima (relativa a la lectura anterior) para considerar
+// que el registro se reinició; caídas menores se tratan como ruido del medidor.
+const resetRatio = 0.5
+
+// rolloverBand es la fracción del máximo del registro que delimita la zona
+// alta (antes del desborde) y la zona baja (después del desborde). Es estrecha
+// para que el cambio de un medidor (p. ej. de 95000 a 10) se trate como
+// reinicio y no como un desborde con un consumo ficticio.
+const rolloverBand = 0.01
+
+// IntervalDeltas convierte lecturas de registros acumulados en consumos por
+// intervalo. Cada lectura resultante contiene la diferencia con la lectura
+// anterior del mismo medidor, por lo que la suma de un periodo equivale a la
+// última lectura menos la primera. La primera lectura de cada medidor no tiene
+// referencia y se descarta, por lo que para no perder el consumo del primer
+// intervalo debe incluirse la última lectura anterior al rango.
+func IntervalDeltas(consumptions []model.Consumption) []model.Consumption {
+	sorted := make([]model.Consumption, len(consumptions))
+	copy(sorted, consumptions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].MeterID != sorted[j].MeterID {
+			return sorted[i].MeterID < sorted[j].MeterID
+		}
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	deltas := make([]model.Consumption, 0, len(sorted))
+	for i := 1; i < len(sorted); i++ {
+		previous, current := sorted[i-1], sorted[i]
+		if previous.MeterID != current.MeterID {
+			continue
+		}
+
+		deltas = append(deltas, model.Consumption{
+			ID:                 current.ID,
+			MeterID:            current.MeterID,
+			Date:               current.Date,
+			ActiveEnergy:       registerDelta(previous.ActiveEnergy, current.ActiveEnergy),
+			ReactiveInductive:  registerDelta(previous.ReactiveInductive, current.ReactiveInductive),
+			ReactiveCapacitive: registerDelta(previous.ReactiveCapacitive, current.ReactiveCapacitive),
+			ExportedEnergy:     registerDelta(previous.ExportedEnergy, current.ExportedEnergy),
+		})
+	}
+
+	return deltas
+}
+
+// registerDelta calcula el consumo entre dos lecturas de un registro. Si el
+// registro estaba en el 1 % superior de una potencia de 10 y vuelve al 1 %
+// inferior se trata como desborde; si cae a menos de la mitad se trata como
+// reinicio desde cero (o cambio de medidor).
+func registerDelta(previous, current float64) float64 {
+	if current >= previous {
+		return current - previous
+	}
+
+	if previous > 0 {
+		registerMax := math.Pow(10, math.Ceil(math.Log10(previous)))
+		if previous >= registerMax*(1-rolloverBand) && current < registerMax*rolloverBand {
+			return registerMax - previous + current
+		}
+	}
+
+	if current < previous*resetRatio {
+		return current
+	}
+
+	return 0
+}
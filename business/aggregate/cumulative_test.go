@@ -0,0 +1,93 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterDelta(t *testing.T) {
+	tests := []struct {
+		name          string
+		previous      float64
+		current       float64
+		expectedDelta float64
+	}{
+		{name: "Increasing register", previous: 100, current: 130, expectedDelta: 30},
+		{name: "Unchanged register", previous: 100, current: 100, expectedDelta: 0},
+		{name: "Rollover of a 5-digit register", previous: 99950, current: 20, expectedDelta: 70},
+		{name: "Rollover of a 3-digit register", previous: 995, current: 3, expectedDelta: 8},
+		{name: "Reset to zero", previous: 130, current: 5, expectedDelta: 5},
+		{name: "Meter swap is a reset, not a rollover", previous: 95000, current: 10, expectedDelta: 10},
+		{name: "Small drop is meter noise", previous: 100, current: 99, expectedDelta: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expectedDelta, registerDelta(tt.previous, tt.current), 1e-9)
+		})
+	}
+}
+
+func TestIntervalDeltas(t *testing.T) {
+	start := time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		consumptions   []model.Consumption
+		expectedDeltas []model.Consumption
+	}{
+		{
+			name: "First reading of each meter only serves as reference",
+			consumptions: []model.Consumption{
+				{ID: "b2", MeterID: 2, ActiveEnergy: 50, Date: start.Add(time.Hour)},
+				{ID: "a2", MeterID: 1, ActiveEnergy: 110, ExportedEnergy: 4, Date: start.Add(time.Hour)},
+				{ID: "a1", MeterID: 1, ActiveEnergy: 100, ExportedEnergy: 1, Date: start},
+			},
+			expectedDeltas: []model.Consumption{
+				{ID: "a2", MeterID: 1, ActiveEnergy: 10, ExportedEnergy: 3, Date: start.Add(time.Hour)},
+			},
+		},
+		{
+			name: "Reading before the range gives the delta of the first interval",
+			consumptions: []model.Consumption{
+				{ID: "before", MeterID: 1, ActiveEnergy: 95, Date: start.Add(-time.Hour)},
+				{ID: "first", MeterID: 1, ActiveEnergy: 100, Date: start.Add(15 * time.Minute)},
+				{ID: "second", MeterID: 1, ActiveEnergy: 120, Date: start.Add(30 * time.Minute)},
+			},
+			expectedDeltas: []model.Consumption{
+				{ID: "first", MeterID: 1, ActiveEnergy: 5, Date: start.Add(15 * time.Minute)},
+				{ID: "second", MeterID: 1, ActiveEnergy: 20, Date: start.Add(30 * time.Minute)},
+			},
+		},
+		{
+			name: "Rollover and reset within the series",
+			consumptions: []model.Consumption{
+				{ID: "1", MeterID: 1, ActiveEnergy: 99990, Date: start},
+				{ID: "2", MeterID: 1, ActiveEnergy: 15, Date: start.Add(time.Hour)},
+				{ID: "3", MeterID: 1, ActiveEnergy: 40, Date: start.Add(2 * time.Hour)},
+				{ID: "4", MeterID: 1, ActiveEnergy: 2, Date: start.Add(3 * time.Hour)},
+			},
+			expectedDeltas: []model.Consumption{
+				{ID: "2", MeterID: 1, ActiveEnergy: 25, Date: start.Add(time.Hour)},
+				{ID: "3", MeterID: 1, ActiveEnergy: 25, Date: start.Add(2 * time.Hour)},
+				{ID: "4", MeterID: 1, ActiveEnergy: 2, Date: start.Add(3 * time.Hour)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deltas := IntervalDeltas(tt.consumptions)
+			assert.Len(t, deltas, len(tt.expectedDeltas))
+			for i := range tt.expectedDeltas {
+				assert.Equal(t, tt.expectedDeltas[i].ID, deltas[i].ID)
+				assert.Equal(t, tt.expectedDeltas[i].Date, deltas[i].Date)
+				assert.InDelta(t, tt.expectedDeltas[i].ActiveEnergy, deltas[i].ActiveEnergy, 1e-9)
+				assert.InDelta(t, tt.expectedDeltas[i].ExportedEnergy, deltas[i].ExportedEnergy, 1e-9)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package aggregate
+
+import (
+	"time"
+)
+
+type HourlyAggregationStrategy struct{}
+
+func (h *HourlyAggregationStrategy) Truncate(date time.Time) time.Time {
+	return truncateClock(date, time.Hour)
+}
+
+func (h *HourlyAggregationStrategy) Next(start time.Time) time.Time {
+	return start.Add(time.Hour)
+}
+
+func (h *HourlyAggregationStrategy) Unit() (string, bool) {
+	return UnitHour, true
+}
+
+func (h *HourlyAggregationStrategy) Label(start time.Time) string {
+	return start.Format("Jan 2 15:04")
+}
+
+// truncateClock trunca la fecha a múltiplos de step según la hora local de su
+// zona, de modo que los periodos empiecen en horas exactas aun con offsets
+// no enteros (p. ej. +05:30).
+func truncateClock(date time.Time, step time.Duration) time.Time {
+	_, offset := date.Zone()
+	shift := time.Duration(offset) * time.Second
+	return date.Add(shift).Truncate(step).Add(-shift)
+}
@@ -0,0 +1,49 @@
+package aggregate
+
+import (
+	"time"
+)
+
+// IntervalAggregationStrategy agrupa en intervalos de duración fija alineados a
+// la medianoche local cuando Step divide el día.
+type IntervalAggregationStrategy struct {
+	Step time.Duration
+}
+
+func (i *IntervalAggregationStrategy) Truncate(date time.Time) time.Time {
+	if !i.dividesDay() {
+		return truncateClock(date, i.Step)
+	}
+	return atClock(date, sinceMidnight(date).Truncate(i.Step))
+}
+
+// Next avanza Step en la hora local, no en tiempo transcurrido, para que los
+// límites coincidan con los de Truncate aunque el día tenga 23 o 25 horas.
+func (i *IntervalAggregationStrategy) Next(start time.Time) time.Time {
+	if !i.dividesDay() {
+		return start.Add(i.Step)
+	}
+	return atClock(start, sinceMidnight(start)+i.Step)
+}
+
+func (i *IntervalAggregationStrategy) Label(start time.Time) string {
+	return start.Format("Jan 2 2006 15:04")
+}
+
+// dividesDay indica si Step reparte el día en intervalos iguales.
+func (i *IntervalAggregationStrategy) dividesDay() bool {
+	return i.Step > 0 && (24*time.Hour)%i.Step == 0
+}
+
+// sinceMidnight es la hora local de la fecha como duración desde medianoche.
+func sinceMidnight(date time.Time) time.Duration {
+	hour, minute, second := date.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+		time.Duration(second)*time.Second + time.Duration(date.Nanosecond())
+}
+
+// atClock devuelve el instante en que el reloj local del día de date marca
+// clock; más de 24h pasa a los días siguientes.
+func atClock(date time.Time, clock time.Duration) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, int(clock), date.Location())
+}
@@ -1,35 +1,23 @@
 package aggregate
 
 import (
-	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"time"
 )
 
 type MonthlyAggregationStrategy struct{}
 
-func (m *MonthlyAggregationStrategy) Aggregate(consumptions []model.Consumption) map[string]model.AggregatedConsumption {
-	aggregation := make(map[string]model.AggregatedConsumption)
-
-	for _, consumption := range consumptions {
-		month := consumption.Date.Format("Jan 2006")
+func (m *MonthlyAggregationStrategy) Truncate(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+}
 
-		if _, exists := aggregation[month]; !exists {
-			aggregation[month] = model.AggregatedConsumption{
-				Period:             []string{month},
-				ActiveEnergy:       []float64{consumption.ActiveEnergy},
-				ReactiveInductive:  []float64{consumption.ReactiveInductive},
-				ReactiveCapacitive: []float64{consumption.ReactiveCapacitive},
-				ExportedEnergy:     []float64{consumption.ExportedEnergy},
-			}
-		} else {
-			aggData := aggregation[month]
-			aggData.ActiveEnergy = append(aggData.ActiveEnergy, consumption.ActiveEnergy)
-			aggData.ReactiveInductive = append(aggData.ReactiveInductive, consumption.ReactiveInductive)
-			aggData.ReactiveCapacitive = append(aggData.ReactiveCapacitive, consumption.ReactiveCapacitive)
-			aggData.ExportedEnergy = append(aggData.ExportedEnergy, consumption.ExportedEnergy)
+func (m *MonthlyAggregationStrategy) Next(start time.Time) time.Time {
+	return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+}
 
-			aggregation[month] = aggData
-		}
-	}
+func (m *MonthlyAggregationStrategy) Unit() (string, bool) {
+	return UnitMonth, true
+}
 
-	return aggregation
+func (m *MonthlyAggregationStrategy) Label(start time.Time) string {
+	return start.Format("Jan 2006")
 }
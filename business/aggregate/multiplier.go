@@ -0,0 +1,19 @@
+package aggregate
+
+import (
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+)
+
+// Scale multiplica las energías de cada lectura por factor, p. ej. la relación
+// de transformación (CT) del medidor.
+func Scale(consumptions []model.Consumption, factor float64) []model.Consumption {
+	scaled := make([]model.Consumption, len(consumptions))
+	for i, consumption := range consumptions {
+		consumption.ActiveEnergy *= factor
+		consumption.ReactiveInductive *= factor
+		consumption.ReactiveCapacitive *= factor
+		consumption.ExportedEnergy *= factor
+		scaled[i] = consumption
+	}
+	return scaled
+}
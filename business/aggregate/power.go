@@ -0,0 +1,44 @@
+package aggregate
+
+import "math"
+
+// DefaultReactivePenaltyRatio es la proporción de energía reactiva inductiva
+// sobre la activa a partir de la cual se penaliza el periodo. Equivale a un
+// factor de potencia de 1/√1.25 ≈ 0.894.
+const DefaultReactivePenaltyRatio = 0.5
+
+// PowerQuality resume la relación entre energía activa y reactiva de un periodo.
+type PowerQuality struct {
+	// PowerFactor es nil cuando el periodo no tiene energía.
+	PowerFactor *float64
+	// ApparentEnergy está en kVAh.
+	ApparentEnergy float64
+	// InductivePenalty indica que la reactiva inductiva supera la proporción
+	// permitida de la activa.
+	InductivePenalty bool
+	// CapacitivePenalty indica que hubo energía reactiva capacitiva, que se
+	// penaliza aparte y sin franja permitida.
+	CapacitivePenalty bool
+	// ReactivePenalty indica que aplica alguna de las dos penalizaciones.
+	ReactivePenalty bool
+}
+
+// AnalyzePower calcula el factor de potencia, la energía aparente y las
+// penalizaciones por energía reactiva. La reactiva capacitiva compensa en
+// parte la inductiva, por lo que la energía aparente usa su diferencia.
+func AnalyzePower(active, reactiveInductive, reactiveCapacitive, penaltyRatio float64) PowerQuality {
+	apparent := math.Hypot(active, reactiveInductive-reactiveCapacitive)
+
+	quality := PowerQuality{
+		ApparentEnergy:    apparent,
+		InductivePenalty:  reactiveInductive > active*penaltyRatio,
+		CapacitivePenalty: reactiveCapacitive > 0,
+	}
+	quality.ReactivePenalty = quality.InductivePenalty || quality.CapacitivePenalty
+	if apparent > 0 {
+		powerFactor := roundRatio(active / apparent)
+		quality.PowerFactor = &powerFactor
+	}
+
+	return quality
+}
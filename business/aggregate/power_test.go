@@ -0,0 +1,43 @@
+package aggregate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzePower(t *testing.T) {
+	tests := []struct {
+		name               string
+		active             float64
+		inductive          float64
+		capacitive         float64
+		expectedFactor     *float64
+		expectedApparent   float64
+		expectedInductive  bool
+		expectedCapacitive bool
+	}{
+		{name: "Only active energy", active: 100, expectedFactor: ratio(1), expectedApparent: 100},
+		{name: "Inductive within the allowed ratio", active: 100, inductive: 50, expectedFactor: ratio(0.8944), expectedApparent: math.Hypot(100, 50)},
+		{name: "Inductive above the allowed ratio", active: 100, inductive: 60, expectedFactor: ratio(0.8575), expectedApparent: math.Hypot(100, 60), expectedInductive: true},
+		{name: "Capacitive offsets inductive", active: 100, inductive: 60, capacitive: 60, expectedFactor: ratio(1), expectedApparent: 100, expectedInductive: true, expectedCapacitive: true},
+		{name: "Only capacitive energy", active: 100, capacitive: 20, expectedFactor: ratio(0.9806), expectedApparent: math.Hypot(100, 20), expectedCapacitive: true},
+		{name: "No energy", expectedApparent: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quality := AnalyzePower(tt.active, tt.inductive, tt.capacitive, DefaultReactivePenaltyRatio)
+			assert.Equal(t, tt.expectedFactor, quality.PowerFactor)
+			assert.InDelta(t, tt.expectedApparent, quality.ApparentEnergy, 1e-9)
+			assert.Equal(t, tt.expectedInductive, quality.InductivePenalty)
+			assert.Equal(t, tt.expectedCapacitive, quality.CapacitivePenalty)
+			assert.Equal(t, tt.expectedInductive || tt.expectedCapacitive, quality.ReactivePenalty)
+		})
+	}
+}
+
+func ratio(value float64) *float64 {
+	return &value
+}
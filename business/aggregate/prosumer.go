@@ -0,0 +1,40 @@
+package aggregate
+
+import "math"
+
+// ProsumerBalance resume el balance entre energía importada y exportada de un
+// medidor con generación propia.
+type ProsumerBalance struct {
+	// NetEnergy es la energía activa menos la exportada; negativa si el periodo
+	// exportó más de lo que consumió de la red.
+	NetEnergy float64
+	// ExportRatio es la fracción de la energía medida que fue exportada. Es nil
+	// cuando el periodo no tiene energía.
+	ExportRatio *float64
+	// ExportCoverage es la fracción de la energía importada que compensan las
+	// exportaciones (1 equivale a balance neto cero). No mide autoconsumo: sin
+	// datos de generación no se sabe cuánta energía se consumió en sitio. Es
+	// nil cuando no hubo importación.
+	ExportCoverage *float64
+}
+
+// AnalyzeProsumer calcula el balance de un periodo a partir de sus totales de
+// energía activa importada y energía exportada.
+func AnalyzeProsumer(active, exported float64) ProsumerBalance {
+	balance := ProsumerBalance{NetEnergy: active - exported}
+
+	if total := active + exported; total > 0 {
+		exportRatio := roundRatio(exported / total)
+		balance.ExportRatio = &exportRatio
+	}
+	if active > 0 {
+		exportCoverage := roundRatio(math.Min(exported, active) / active)
+		balance.ExportCoverage = &exportCoverage
+	}
+
+	return balance
+}
+
+func roundRatio(ratio float64) float64 {
+	return math.Round(ratio*10000) / 10000
+}
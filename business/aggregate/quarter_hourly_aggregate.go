@@ -0,0 +1,21 @@
+package aggregate
+
+import (
+	"time"
+)
+
+// QuarterHourlyAggregationStrategy agrupa en intervalos de 15 minutos, el
+// intervalo de facturación.
+type QuarterHourlyAggregationStrategy struct{}
+
+func (q *QuarterHourlyAggregationStrategy) Truncate(date time.Time) time.Time {
+	return truncateClock(date, 15*time.Minute)
+}
+
+func (q *QuarterHourlyAggregationStrategy) Next(start time.Time) time.Time {
+	return start.Add(15 * time.Minute)
+}
+
+func (q *QuarterHourlyAggregationStrategy) Label(start time.Time) string {
+	return start.Format("Jan 2 15:04")
+}
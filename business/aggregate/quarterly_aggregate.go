@@ -0,0 +1,49 @@
+package aggregate
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuarterlyAggregationStrategy agrupa por trimestres. Si FiscalYearStart es
+// distinto de enero los trimestres se cuentan desde el inicio del año fiscal.
+type QuarterlyAggregationStrategy struct {
+	FiscalYearStart time.Month
+}
+
+func (q *QuarterlyAggregationStrategy) Truncate(date time.Time) time.Time {
+	monthsIntoYear := monthsIntoFiscalYear(date.Month(), q.FiscalYearStart)
+	return time.Date(date.Year(), date.Month()-time.Month(monthsIntoYear%3), 1, 0, 0, 0, 0, date.Location())
+}
+
+func (q *QuarterlyAggregationStrategy) Next(start time.Time) time.Time {
+	return time.Date(start.Year(), start.Month()+3, 1, 0, 0, 0, 0, start.Location())
+}
+
+func (q *QuarterlyAggregationStrategy) Label(start time.Time) string {
+	quarter := monthsIntoFiscalYear(start.Month(), q.FiscalYearStart)/3 + 1
+	if isCalendarYear(q.FiscalYearStart) {
+		return fmt.Sprintf("Q%d %d", quarter, start.Year())
+	}
+	return fmt.Sprintf("FY%d Q%d", fiscalYear(start, q.FiscalYearStart), quarter)
+}
+
+func isCalendarYear(fiscalYearStart time.Month) bool {
+	return fiscalYearStart == 0 || fiscalYearStart == time.January
+}
+
+// monthsIntoFiscalYear devuelve cuántos meses han pasado desde el inicio del año fiscal.
+func monthsIntoFiscalYear(month, fiscalYearStart time.Month) int {
+	if isCalendarYear(fiscalYearStart) {
+		fiscalYearStart = time.January
+	}
+	return (int(month) - int(fiscalYearStart) + 12) % 12
+}
+
+// fiscalYear nombra el año fiscal por el año calendario en que termina.
+func fiscalYear(date time.Time, fiscalYearStart time.Month) int {
+	if isCalendarYear(fiscalYearStart) || date.Month() < fiscalYearStart {
+		return date.Year()
+	}
+	return date.Year() + 1
+}
@@ -0,0 +1,81 @@
+package aggregate
+
+import (
+	"math"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+)
+
+// Reducer colapsa las lecturas de un periodo en un único valor.
+type Reducer interface {
+	Reduce(values []float64) float64
+}
+
+type SumReducer struct{}
+
+func (s *SumReducer) Reduce(values []float64) float64 {
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
+type MeanReducer struct{}
+
+func (m *MeanReducer) Reduce(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return (&SumReducer{}).Reduce(values) / float64(len(values))
+}
+
+type MinReducer struct{}
+
+func (m *MinReducer) Reduce(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	min := math.Inf(1)
+	for _, value := range values {
+		min = math.Min(min, value)
+	}
+	return min
+}
+
+type MaxReducer struct{}
+
+func (m *MaxReducer) Reduce(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	max := math.Inf(-1)
+	for _, value := range values {
+		max = math.Max(max, value)
+	}
+	return max
+}
+
+type CountReducer struct{}
+
+func (c *CountReducer) Reduce(values []float64) float64 {
+	return float64(len(values))
+}
+
+// Reduce aplica el reducer a cada periodo, dejando un solo valor por serie.
+func Reduce(series []model.AggregatedConsumption, reducer Reducer) []model.AggregatedConsumption {
+	reduced := make([]model.AggregatedConsumption, 0, len(series))
+
+	for _, aggData := range series {
+		reduced = append(reduced, model.AggregatedConsumption{
+			Start:              aggData.Start,
+			Period:             aggData.Period,
+			ActiveEnergy:       []float64{reducer.Reduce(aggData.ActiveEnergy)},
+			ReactiveInductive:  []float64{reducer.Reduce(aggData.ReactiveInductive)},
+			ReactiveCapacitive: []float64{reducer.Reduce(aggData.ReactiveCapacitive)},
+			ExportedEnergy:     []float64{reducer.Reduce(aggData.ExportedEnergy)},
+		})
+	}
+
+	return reduced
+}
@@ -0,0 +1,154 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const minIntervalStep = time.Minute
+
+// Options agrupa los parámetros de la consulta con que se construye una estrategia.
+type Options struct {
+	WeekStart       time.Weekday
+	Step            time.Duration
+	FiscalYearStart time.Month
+}
+
+// StrategyFactory construye una estrategia a partir de las opciones de la consulta.
+type StrategyFactory func(options Options) (AggregationStrategy, error)
+
+// StrategyInfo describe una estrategia registrada y los parámetros que acepta.
+type StrategyInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Parameters  []string `json:"parameters"`
+}
+
+type registeredStrategy struct {
+	info    StrategyInfo
+	factory StrategyFactory
+}
+
+// Registry guarda las estrategias de agregación disponibles por nombre.
+type Registry struct {
+	mu         sync.RWMutex
+	strategies map[string]registeredStrategy
+	names      []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		strategies: make(map[string]registeredStrategy),
+	}
+}
+
+// NewDefaultRegistry crea un registro con todas las estrategias incluidas.
+func NewDefaultRegistry() *Registry {
+	registry := NewRegistry()
+
+	registry.MustRegister(StrategyInfo{Name: "quarter_hourly", Description: "Intervalos de 15 minutos (intervalo de facturación)"},
+		func(Options) (AggregationStrategy, error) { return &QuarterHourlyAggregationStrategy{}, nil })
+	registry.MustRegister(StrategyInfo{Name: "hourly", Description: "Horas"},
+		func(Options) (AggregationStrategy, error) { return &HourlyAggregationStrategy{}, nil })
+	registry.MustRegister(StrategyInfo{Name: "daily", Description: "Días"},
+		func(Options) (AggregationStrategy, error) { return &DailyAggregationStrategy{}, nil })
+	registry.MustRegister(StrategyInfo{Name: "weekly", Description: "Semanas ISO-8601 o iniciadas en domingo", Parameters: []string{"week_start"}},
+		func(options Options) (AggregationStrategy, error) {
+			return &WeeklyAggregationStrategy{WeekStart: options.WeekStart}, nil
+		})
+	registry.MustRegister(StrategyInfo{Name: "monthly", Description: "Meses"},
+		func(Options) (AggregationStrategy, error) { return &MonthlyAggregationStrategy{}, nil })
+	registry.MustRegister(StrategyInfo{Name: "quarterly", Description: "Trimestres calendario o fiscales", Parameters: []string{"fiscal_year_start"}},
+		func(options Options) (AggregationStrategy, error) {
+			return &QuarterlyAggregationStrategy{FiscalYearStart: options.FiscalYearStart}, nil
+		})
+	registry.MustRegister(StrategyInfo{Name: "yearly", Description: "Años calendario o fiscales", Parameters: []string{"fiscal_year_start"}},
+		func(options Options) (AggregationStrategy, error) {
+			return &YearlyAggregationStrategy{FiscalYearStart: options.FiscalYearStart}, nil
+		})
+	registry.MustRegister(StrategyInfo{Name: "interval", Description: "Intervalos de duración fija", Parameters: []string{"step"}},
+		func(options Options) (AggregationStrategy, error) {
+			if options.Step == 0 {
+				return nil, errors.New("step is required for kind_period interval")
+			}
+			if options.Step < minIntervalStep {
+				return nil, fmt.Errorf("invalid step: %s", options.Step)
+			}
+			return &IntervalAggregationStrategy{Step: options.Step}, nil
+		})
+
+	return registry
+}
+
+// Register agrega una estrategia al registro. Falla si el nombre ya existe.
+func (r *Registry) Register(info StrategyInfo, factory StrategyFactory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.strategies[info.Name]; exists {
+		return fmt.Errorf("strategy already registered: %s", info.Name)
+	}
+	// Sin parámetros se lista un arreglo vacío y no null.
+	if info.Parameters == nil {
+		info.Parameters = []string{}
+	}
+
+	r.strategies[info.Name] = registeredStrategy{info: info, factory: factory}
+	r.names = append(r.names, info.Name)
+	return nil
+}
+
+// MustRegister es como Register pero entra en pánico si falla.
+func (r *Registry) MustRegister(info StrategyInfo, factory StrategyFactory) {
+	if err := r.Register(info, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Info devuelve la descripción de la estrategia registrada con el nombre indicado.
+func (r *Registry) Info(name string) (StrategyInfo, error) {
+	r.mu.RLock()
+	strategy, exists := r.strategies[name]
+	r.mu.RUnlock()
+
+	if !exists {
+		return StrategyInfo{}, fmt.Errorf("invalid kind_period: %s", name)
+	}
+	return strategy.info, nil
+}
+
+// Build construye la estrategia registrada con el nombre indicado.
+func (r *Registry) Build(name string, options Options) (AggregationStrategy, error) {
+	r.mu.RLock()
+	strategy, exists := r.strategies[name]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("invalid kind_period: %s", name)
+	}
+	return strategy.factory(options)
+}
+
+// List devuelve las estrategias en el orden en que fueron registradas.
+func (r *Registry) List() []StrategyInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]StrategyInfo, 0, len(r.names))
+	for _, name := range r.names {
+		infos = append(infos, r.strategies[name].info)
+	}
+	return infos
+}
+
+// Accepts indica si la estrategia declara el parámetro de consulta indicado.
+func (info StrategyInfo) Accepts(parameter string) bool {
+	for _, name := range info.Parameters {
+		if name == parameter {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_List(t *testing.T) {
+	infos := NewDefaultRegistry().List()
+
+	body, err := json.Marshal(infos[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "hourly", "description": "Horas", "parameters": []}`, string(body))
+	assert.Equal(t, []string{"week_start"}, infos[3].Parameters)
+}
@@ -3,43 +3,29 @@ package aggregate
 import (
 	"fmt"
 	"time"
-
-	"github.com/SaidHernandez/bia-comsumtion/business/model"
 )
 
-type WeeklyAggregationStrategy struct{}
-
-func (w *WeeklyAggregationStrategy) Aggregate(consumptions []model.Consumption) map[string]model.AggregatedConsumption {
-	aggregation := make(map[string]model.AggregatedConsumption)
-
-	formatWeekRange := func(date time.Time) string {
-		startOfWeek := date.AddDate(0, 0, -int(date.Weekday()))
-		endOfWeek := startOfWeek.AddDate(0, 0, 6)
-		return fmt.Sprintf("%s %d - %s %d", startOfWeek.Format("Jan"), startOfWeek.Day(), endOfWeek.Format("Jan"), endOfWeek.Day())
-	}
-
-	for _, consumption := range consumptions {
-		period := formatWeekRange(consumption.Date)
-
-		if _, exists := aggregation[period]; !exists {
-			aggregation[period] = model.AggregatedConsumption{
-				Period:             []string{period},
-				ActiveEnergy:       []float64{},
-				ReactiveInductive:  []float64{},
-				ReactiveCapacitive: []float64{},
-				ExportedEnergy:     []float64{},
-			}
-		}
+// WeeklyAggregationStrategy agrupa por semanas que empiezan en WeekStart. Las
+// semanas que empiezan el lunes siguen la numeración ISO-8601.
+type WeeklyAggregationStrategy struct {
+	WeekStart time.Weekday
+}
 
-		aggData := aggregation[period]
+func (w *WeeklyAggregationStrategy) Truncate(date time.Time) time.Time {
+	offset := (int(date.Weekday()) - int(w.WeekStart) + 7) % 7
+	return time.Date(date.Year(), date.Month(), date.Day()-offset, 0, 0, 0, 0, date.Location())
+}
 
-		aggData.ActiveEnergy = append(aggData.ActiveEnergy, consumption.ActiveEnergy)
-		aggData.ReactiveInductive = append(aggData.ReactiveInductive, consumption.ReactiveInductive)
-		aggData.ReactiveCapacitive = append(aggData.ReactiveCapacitive, consumption.ReactiveCapacitive)
-		aggData.ExportedEnergy = append(aggData.ExportedEnergy, consumption.ExportedEnergy)
+func (w *WeeklyAggregationStrategy) Next(start time.Time) time.Time {
+	return time.Date(start.Year(), start.Month(), start.Day()+7, 0, 0, 0, 0, start.Location())
+}
 
-		aggregation[period] = aggData
+func (w *WeeklyAggregationStrategy) Label(start time.Time) string {
+	if w.WeekStart == time.Monday {
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
 	}
 
-	return aggregation
+	endOfWeek := start.AddDate(0, 0, 6)
+	return fmt.Sprintf("%s - %s", start.Format("Jan 2 2006"), endOfWeek.Format("Jan 2 2006"))
 }
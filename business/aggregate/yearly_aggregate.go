@@ -0,0 +1,34 @@
+package aggregate
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// YearlyAggregationStrategy agrupa por años calendario o, si FiscalYearStart es
+// distinto de enero, por años fiscales.
+type YearlyAggregationStrategy struct {
+	FiscalYearStart time.Month
+}
+
+func (y *YearlyAggregationStrategy) Truncate(date time.Time) time.Time {
+	monthsIntoYear := monthsIntoFiscalYear(date.Month(), y.FiscalYearStart)
+	return time.Date(date.Year(), date.Month()-time.Month(monthsIntoYear), 1, 0, 0, 0, 0, date.Location())
+}
+
+func (y *YearlyAggregationStrategy) Next(start time.Time) time.Time {
+	return time.Date(start.Year()+1, start.Month(), 1, 0, 0, 0, 0, start.Location())
+}
+
+// Unit solo corresponde a una unidad de calendario cuando el año no es fiscal.
+func (y *YearlyAggregationStrategy) Unit() (string, bool) {
+	return UnitYear, isCalendarYear(y.FiscalYearStart)
+}
+
+func (y *YearlyAggregationStrategy) Label(start time.Time) string {
+	if isCalendarYear(y.FiscalYearStart) {
+		return strconv.Itoa(start.Year())
+	}
+	return fmt.Sprintf("FY%d", fiscalYear(start, y.FiscalYearStart))
+}
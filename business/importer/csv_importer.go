@@ -0,0 +1,431 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+)
+
+// DefaultBatchSize es la cantidad de filas que se guardan por transacción.
+const DefaultBatchSize = 500
+
+// Columnas reconocidas en la cabecera del archivo.
+const (
+	ColumnID                 = "id"
+	ColumnMeterID            = "meter_id"
+	ColumnDate               = "date"
+	ColumnActiveEnergy       = "active_energy"
+	ColumnReactiveInductive  = "reactive_inductive"
+	ColumnReactiveCapacitive = "reactive_capacitive"
+	ColumnExportedEnergy     = "exported_energy"
+)
+
+var requiredColumns = []string{ColumnID, ColumnMeterID, ColumnDate, ColumnActiveEnergy}
+
+// legacyHeader es el orden de las columnas de los archivos sin cabecera, como
+// los exportados por el sistema anterior.
+var legacyHeader = []string{ColumnID, ColumnMeterID, ColumnActiveEnergy, ColumnDate}
+
+var knownColumns = map[string]bool{
+	ColumnID:                 true,
+	ColumnMeterID:            true,
+	ColumnDate:               true,
+	ColumnActiveEnergy:       true,
+	ColumnReactiveInductive:  true,
+	ColumnReactiveCapacitive: true,
+	ColumnExportedEnergy:     true,
+}
+
+// dateLayouts son los formatos de fecha aceptados; sin zona horaria se asume UTC.
+var dateLayouts = []string{
+	"2006-01-02 15:04:05-07",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+}
+
+// Reject describe una fila descartada por no ser válida.
+type Reject struct {
+	Line   int      `json:"line"`
+	Record []string `json:"record,omitempty"`
+	Reason string   `json:"reason"`
+}
+
+// Summary resume el resultado de una importación.
+type Summary struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	// Skipped cuenta las lecturas descartadas en favor de otra con el mismo ID
+	// o el mismo medidor y fecha.
+	Skipped  int      `json:"skipped"`
+	Rejected int      `json:"rejected"`
+	Rejects  []Reject `json:"rejects,omitempty"`
+}
+
+// Importer carga lecturas de consumo desde archivos CSV y las guarda con el
+// repositorio, que resuelve los duplicados según su política.
+type Importer struct {
+	readings  repository.ReadingRepositoryInterface
+	batchSize int
+}
+
+func NewImporter(readings repository.ReadingRepositoryInterface) *Importer {
+	return &Importer{readings: readings, batchSize: DefaultBatchSize}
+}
+
+// SetBatchSize cambia la cantidad de filas por transacción; valores menores a 1
+// restauran DefaultBatchSize.
+func (i *Importer) SetBatchSize(size int) {
+	if size < 1 {
+		size = DefaultBatchSize
+	}
+	i.batchSize = size
+}
+
+// ImportCSV lee el archivo fila a fila y guarda las lecturas en lotes
+// transaccionales. Las filas inválidas se reportan en el resumen sin detener la
+// importación. Las lecturas cuyo ID ya existe se actualizan, por lo que el mismo
+// archivo puede importarse varias veces; las de un medidor y fecha ya cargados
+// con otro ID se resuelven según la política de duplicados del repositorio.
+// Devuelve error sólo si la cabecera no es válida, si falla la lectura o si
+// falla un lote, en cuyo caso el resumen cuenta lo guardado hasta entonces. Un archivo sin cabecera se lee
+// con las columnas id, meter_id, active_energy y date en ese orden.
+func (i *Importer) ImportCSV(ctx context.Context, r io.Reader) (*Summary, error) {
+	scanner, err := newScanner(r, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{}
+	batch := make([]row, 0, i.batchSize)
+	for {
+		current, err := scanner.next(summary)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+
+		batch = append(batch, current)
+		if len(batch) == i.batchSize {
+			if err := i.saveBatch(ctx, batch, summary); err != nil {
+				return summary, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := i.saveBatch(ctx, batch, summary); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// DecodeCSV lee y valida las lecturas de un CSV sin guardarlas. Si meterID no
+// es cero la columna meter_id es opcional y se rechazan las filas de otros
+// medidores.
+func DecodeCSV(r io.Reader, meterID int) ([]model.Consumption, []Reject, error) {
+	scanner, err := newScanner(r, meterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary := &Summary{}
+	var consumptions []model.Consumption
+	for {
+		current, err := scanner.next(summary)
+		if errors.Is(err, io.EOF) {
+			return consumptions, summary.Rejects, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		consumptions = append(consumptions, current.consumption)
+	}
+}
+
+// scanner recorre las filas de un CSV ya validada su cabecera.
+type scanner struct {
+	reader  *csv.Reader
+	header  []string
+	columns columnIndex
+	meterID int
+	// pending es la primera fila de un archivo sin cabecera, que todavía no se
+	// entregó como dato.
+	pending []string
+}
+
+func newScanner(r io.Reader, meterID int) (*scanner, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	// Sin ninguna columna conocida la primera fila ya es un dato y las columnas
+	// siguen el orden de legacyHeader.
+	var pending []string
+	if !isHeader(header) {
+		pending, header = header, legacyHeader
+	}
+	columns, err := parseHeader(header, meterID == 0)
+	if err != nil {
+		return nil, err
+	}
+	return &scanner{reader: reader, header: header, columns: columns, meterID: meterID, pending: pending}, nil
+}
+
+// isHeader indica si la fila nombra alguna columna conocida.
+func isHeader(record []string) bool {
+	for _, name := range record {
+		if knownColumns[normalizeColumn(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// next devuelve la siguiente fila válida o io.EOF al terminar. Las filas
+// inválidas se agregan al reporte de rechazos del resumen.
+func (s *scanner) next(summary *Summary) (row, error) {
+	for {
+		record, line, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return row{}, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			summary.reject(parseErr.StartLine, record, parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return row{}, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+
+		if len(record) != len(s.header) {
+			summary.reject(line, record, fmt.Sprintf("expected %d fields, got %d", len(s.header), len(record)))
+			continue
+		}
+		consumption, err := s.columns.consumption(record, s.meterID)
+		if err != nil {
+			summary.reject(line, record, err.Error())
+			continue
+		}
+		return row{line: line, record: record, consumption: consumption}, nil
+	}
+}
+
+// read devuelve la siguiente fila del archivo y su número de línea, empezando
+// por la fila pendiente si el archivo no tiene cabecera.
+func (s *scanner) read() ([]string, int, error) {
+	if s.pending != nil {
+		record := s.pending
+		s.pending = nil
+		return record, 1, nil
+	}
+	record, err := s.reader.Read()
+	if err != nil {
+		return record, 0, err
+	}
+	line, _ := s.reader.FieldPos(0)
+	return record, line, nil
+}
+
+// row es una fila válida del archivo junto con su posición para los reportes.
+type row struct {
+	line        int
+	record      []string
+	consumption model.Consumption
+}
+
+// saveBatch guarda las lecturas del lote en una transacción del repositorio.
+// El resumen se actualiza sólo si la transacción se confirma.
+func (i *Importer) saveBatch(ctx context.Context, batch []row, summary *Summary) error {
+	readings := make([]model.Consumption, len(batch))
+	for index, current := range batch {
+		readings[index] = current.consumption
+	}
+
+	result, err := i.readings.ImportReadings(ctx, readings)
+	if err != nil {
+		return err
+	}
+
+	summary.Inserted += result.Inserted
+	summary.Updated += result.Updated
+	summary.Skipped += result.Skipped
+	for _, reject := range result.Rejects {
+		current := batch[reject.Index]
+		summary.reject(current.line, current.record, reject.Reason)
+	}
+	return nil
+}
+
+func (s *Summary) reject(line int, record []string, reason string) {
+	s.Rejected++
+	s.Rejects = append(s.Rejects, Reject{Line: line, Record: record, Reason: reason})
+}
+
+// WriteRejects escribe el reporte de filas rechazadas como CSV con las columnas
+// line, reason y record.
+func WriteRejects(w io.Writer, rejects []Reject) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"line", "reason", "record"}); err != nil {
+		return err
+	}
+	for _, reject := range rejects {
+		row := []string{strconv.Itoa(reject.Line), reject.Reason, strings.Join(reject.Record, ",")}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// columnIndex asocia cada columna conocida con su posición en la cabecera.
+type columnIndex map[string]int
+
+// parseHeader ubica las columnas conocidas; meter_id es obligatoria sólo si
+// requireMeter es true.
+func parseHeader(header []string, requireMeter bool) (columnIndex, error) {
+	columns := make(columnIndex)
+	for position, name := range header {
+		name = normalizeColumn(name)
+		if !knownColumns[name] {
+			continue
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column in CSV header: %s", name)
+		}
+		columns[name] = position
+	}
+
+	var missing []string
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok && (requireMeter || name != ColumnMeterID) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns in CSV header: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// normalizeColumn quita la marca de orden de bytes, los espacios y las mayúsculas
+// del nombre de una columna.
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
+
+// value devuelve el valor de la columna o "" si no está en la cabecera.
+func (c columnIndex) value(record []string, name string) string {
+	position, ok := c[name]
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(record[position])
+}
+
+// consumption convierte la fila en lectura y la valida. Las columnas de
+// energía reactiva y exportada son opcionales y valen 0 si están vacías. Si
+// meterID no es cero se usa cuando la fila no indica el medidor.
+func (c columnIndex) consumption(record []string, meterID int) (model.Consumption, error) {
+	consumption := model.Consumption{ID: c.value(record, ColumnID), MeterID: meterID}
+
+	if value := c.value(record, ColumnMeterID); value != "" || meterID == 0 {
+		rowMeterID, err := strconv.Atoi(value)
+		if err != nil {
+			return model.Consumption{}, fmt.Errorf("invalid meter_id: %q", value)
+		}
+		if meterID != 0 && rowMeterID != meterID {
+			return model.Consumption{}, fmt.Errorf("meter_id %d does not match meter %d", rowMeterID, meterID)
+		}
+		consumption.MeterID = rowMeterID
+	}
+
+	date, err := parseDate(c.value(record, ColumnDate))
+	if err != nil {
+		return model.Consumption{}, err
+	}
+	consumption.Date = date
+
+	fields := []struct {
+		name     string
+		target   *float64
+		required bool
+	}{
+		{ColumnActiveEnergy, &consumption.ActiveEnergy, true},
+		{ColumnReactiveInductive, &consumption.ReactiveInductive, false},
+		{ColumnReactiveCapacitive, &consumption.ReactiveCapacitive, false},
+		{ColumnExportedEnergy, &consumption.ExportedEnergy, false},
+	}
+	for _, field := range fields {
+		value := c.value(record, field.name)
+		if value == "" && !field.required {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return model.Consumption{}, fmt.Errorf("invalid %s: %q", field.name, value)
+		}
+		*field.target = number
+	}
+	return consumption, ValidateConsumption(consumption)
+}
+
+// ValidateConsumption aplica las reglas de una lectura: ID y fecha obligatorios,
+// medidor positivo y energías finitas y no negativas.
+func ValidateConsumption(consumption model.Consumption) error {
+	if strings.TrimSpace(consumption.ID) == "" {
+		return errors.New("id is required")
+	}
+	if consumption.MeterID < 1 {
+		return fmt.Errorf("invalid meter_id: %d", consumption.MeterID)
+	}
+	if consumption.Date.IsZero() {
+		return errors.New("date is required")
+	}
+
+	energies := []struct {
+		name  string
+		value float64
+	}{
+		{ColumnActiveEnergy, consumption.ActiveEnergy},
+		{ColumnReactiveInductive, consumption.ReactiveInductive},
+		{ColumnReactiveCapacitive, consumption.ReactiveCapacitive},
+		{ColumnExportedEnergy, consumption.ExportedEnergy},
+	}
+	for _, energy := range energies {
+		if math.IsNaN(energy.value) || math.IsInf(energy.value, 0) || energy.value < 0 {
+			return fmt.Errorf("invalid %s: %g", energy.name, energy.value)
+		}
+	}
+	return nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %q", value)
+}
@@ -0,0 +1,253 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImporter_ImportCSV(t *testing.T) {
+	conn := dbtest.Open(t)
+	importer := NewImporter(repository.NewConsumptionRepository(conn))
+	importer.SetBatchSize(2)
+
+	file := strings.Join([]string{
+		"Meter_ID,id,date,active_energy,reactive_inductive,reactive_capacitive,exported_energy,notes",
+		"1,a,2023-07-04 13:59:27+00,10.5,1,2,3,ok",
+		"1,b,2023-07-04T14:00:00-05:00,11,,,,",
+		"2,c,2023-07-04 15:00:00,12,4,5,6,",
+		"x,d,2023-07-04 15:00:00,12,4,5,6,",
+		"2,,2023-07-04 15:00:00,12,4,5,6,",
+		"2,e,04/07/2023,12,4,5,6,",
+		"2,f,2023-07-04 15:00:00,-1,4,5,6,",
+		"2,g,2023-07-04 15:00:00,12,NaN,5,6,",
+		"2,h,2023-07-04 15:00:00",
+		`2,"i,2023-07-04 15:00:00,12,4,5,6,`,
+	}, "\n")
+
+	summary, err := importer.ImportCSV(context.Background(), strings.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Inserted)
+	assert.Equal(t, 0, summary.Skipped)
+	assert.Equal(t, 7, summary.Rejected)
+
+	lines := make([]int, len(summary.Rejects))
+	for i, reject := range summary.Rejects {
+		lines[i] = reject.Line
+	}
+	assert.Equal(t, []int{5, 6, 7, 8, 9, 10, 11}, lines)
+	assert.Equal(t, `invalid meter_id: "x"`, summary.Rejects[0].Reason)
+	assert.Equal(t, "id is required", summary.Rejects[1].Reason)
+	assert.Equal(t, `invalid date: "04/07/2023"`, summary.Rejects[2].Reason)
+	assert.Equal(t, "invalid active_energy: -1", summary.Rejects[3].Reason)
+	assert.Equal(t, "invalid reactive_inductive: NaN", summary.Rejects[4].Reason)
+	assert.Equal(t, "expected 8 fields, got 3", summary.Rejects[5].Reason)
+
+	var consumptions []model.Consumption
+	require.NoError(t, conn.Order("id").Find(&consumptions).Error)
+	require.Len(t, consumptions, 3)
+	assert.Equal(t, model.Consumption{
+		ID: "a", MeterID: 1, Date: time.Date(2023, 7, 4, 13, 59, 27, 0, time.UTC),
+		ActiveEnergy: 10.5, ReactiveInductive: 1, ReactiveCapacitive: 2, ExportedEnergy: 3,
+	}, normalize(consumptions[0]))
+	assert.Equal(t, model.Consumption{
+		ID: "b", MeterID: 1, Date: time.Date(2023, 7, 4, 19, 0, 0, 0, time.UTC), ActiveEnergy: 11,
+	}, normalize(consumptions[1]))
+
+	var meters []model.Meter
+	require.NoError(t, conn.Order("id").Find(&meters).Error)
+	require.Len(t, meters, 2)
+	assert.Equal(t, "1", meters[0].Serial)
+	assert.Equal(t, model.MeterTypeConsumer, meters[1].Type)
+}
+
+func TestImporter_ImportCSV_IsRerunnable(t *testing.T) {
+	conn := dbtest.Open(t)
+	importer := NewImporter(repository.NewConsumptionRepository(conn))
+	file := "id,meter_id,active_energy,date\na,1,10,2023-07-04 13:59:27+00\nb,1,11,2023-07-04 14:59:27+00\n"
+
+	_, err := importer.ImportCSV(context.Background(), strings.NewReader(file))
+	require.NoError(t, err)
+	summary, err := importer.ImportCSV(context.Background(), strings.NewReader(strings.Replace(file, ",11,", ",12,", 1)))
+	require.NoError(t, err)
+
+	assert.Equal(t, Summary{Updated: 2}, *summary)
+	var consumptions []model.Consumption
+	require.NoError(t, conn.Order("id").Find(&consumptions).Error)
+	require.Len(t, consumptions, 2)
+	assert.Equal(t, 12.0, consumptions[1].ActiveEnergy)
+}
+
+func TestImporter_ImportCSV_WithoutHeader(t *testing.T) {
+	conn := dbtest.Open(t)
+	file := "a,1,10,2023-07-04 13:59:27+00\nb,1,x,2023-07-04 14:59:27+00\nc,2,12,2023-07-04 15:59:27+00\n"
+
+	summary, err := NewImporter(repository.NewConsumptionRepository(conn)).ImportCSV(context.Background(), strings.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Inserted)
+	assert.Equal(t, []Reject{{Line: 2, Record: []string{"b", "1", "x", "2023-07-04 14:59:27+00"}, Reason: `invalid active_energy: "x"`}}, summary.Rejects)
+
+	var consumptions []model.Consumption
+	require.NoError(t, conn.Order("id").Find(&consumptions).Error)
+	require.Len(t, consumptions, 2)
+	assert.Equal(t, model.Consumption{
+		ID: "a", MeterID: 1, Date: time.Date(2023, 7, 4, 13, 59, 27, 0, time.UTC), ActiveEnergy: 10,
+	}, normalize(consumptions[0]))
+	assert.Equal(t, 2, consumptions[1].MeterID)
+}
+
+func TestImporter_ImportCSV_DuplicatePolicy(t *testing.T) {
+	stored := "id,meter_id,active_energy,date\nstored,1,1,2023-07-04 10:00:00+00\n"
+	file := strings.Join([]string{
+		"id,meter_id,active_energy,date",
+		"new,1,2,2023-07-04T05:00:00-05:00",
+		"first,2,3,2023-07-04 10:00:00+00",
+		"second,2,4,2023-07-04 10:00:00+00",
+		"first,2,5,2023-07-04 11:00:00+00",
+	}, "\n")
+
+	tests := []struct {
+		name            string
+		policy          repository.DuplicatePolicy
+		expectedSummary Summary
+		expectedIDs     []string
+	}{
+		{
+			name:            "Keep first",
+			policy:          repository.KeepFirst,
+			expectedSummary: Summary{Inserted: 1, Skipped: 3},
+			expectedIDs:     []string{"first", "stored"},
+		},
+		{
+			name:            "Keep last",
+			policy:          repository.KeepLast,
+			expectedSummary: Summary{Inserted: 3, Skipped: 2},
+			expectedIDs:     []string{"first", "new", "second"},
+		},
+		{
+			name:   "Reject",
+			policy: repository.RejectDuplicates,
+			expectedSummary: Summary{Inserted: 1, Skipped: 1, Rejected: 2, Rejects: []Reject{
+				{Line: 4, Record: []string{"second", "2", "4", "2023-07-04 10:00:00+00"}, Reason: "duplicate reading for meter 2 at 2023-07-04T10:00:00Z (id first)"},
+				{Line: 2, Record: []string{"new", "1", "2", "2023-07-04T05:00:00-05:00"}, Reason: "duplicate reading for meter 1 at 2023-07-04T10:00:00Z (id stored)"},
+			}},
+			expectedIDs: []string{"first", "stored"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dbtest.Open(t)
+			readings := repository.NewConsumptionRepository(conn)
+			importer := NewImporter(readings)
+			_, err := importer.ImportCSV(context.Background(), strings.NewReader(stored))
+			require.NoError(t, err)
+
+			readings.SetDuplicatePolicy(tt.policy)
+			summary, err := importer.ImportCSV(context.Background(), strings.NewReader(file))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSummary, *summary)
+
+			var ids []string
+			require.NoError(t, conn.Model(&model.Consumption{}).Order("id").Pluck("id", &ids).Error)
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestImporter_ImportCSV_InvalidHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		expectedErr string
+	}{
+		{name: "Empty file", file: "", expectedErr: "CSV file is empty"},
+		{name: "Missing columns", file: "id,meter_id\n", expectedErr: "missing required columns in CSV header: date, active_energy"},
+		{name: "Duplicate column", file: "id,meter_id,date,active_energy,ID\n", expectedErr: "duplicate column in CSV header: id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := NewImporter(repository.NewConsumptionRepository(dbtest.Open(t))).ImportCSV(context.Background(), strings.NewReader(tt.file))
+			assert.Nil(t, summary)
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestImporter_ImportCSV_Canceled(t *testing.T) {
+	conn := dbtest.Open(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	summary, err := NewImporter(repository.NewConsumptionRepository(conn)).ImportCSV(ctx, strings.NewReader("id,meter_id,active_energy,date\na,1,10,2023-07-04 13:59:27+00\n"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, summary.Inserted)
+}
+
+func TestWriteRejects(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteRejects(&buffer, []Reject{{Line: 3, Record: []string{"a", "x"}, Reason: `invalid meter_id: "x"`}})
+	require.NoError(t, err)
+	assert.Equal(t, "line,reason,record\n3,\"invalid meter_id: \"\"x\"\"\",\"a,x\"\n", buffer.String())
+}
+
+// normalize deja la fecha en UTC para compararla con los valores esperados.
+func normalize(consumption model.Consumption) model.Consumption {
+	consumption.Date = consumption.Date.UTC()
+	return consumption
+}
+
+func TestDecodeCSV(t *testing.T) {
+	file := strings.Join([]string{
+		"id,date,active_energy",
+		"a,2023-07-04 13:59:27+00,10",
+		"b,2023-07-04 14:59:27+00,",
+	}, "\n")
+
+	consumptions, rejects, err := DecodeCSV(strings.NewReader(file), 7)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Consumption{{ID: "a", MeterID: 7, Date: time.Date(2023, 7, 4, 13, 59, 27, 0, time.UTC), ActiveEnergy: 10}}, consumptions)
+	assert.Equal(t, []Reject{{Line: 3, Record: []string{"b", "2023-07-04 14:59:27+00", ""}, Reason: `invalid active_energy: ""`}}, rejects)
+
+	_, rejects, err = DecodeCSV(strings.NewReader("id,meter_id,date,active_energy\na,8,2023-07-04 13:59:27+00,10\n"), 7)
+	require.NoError(t, err)
+	assert.Equal(t, "meter_id 8 does not match meter 7", rejects[0].Reason)
+
+	_, _, err = DecodeCSV(strings.NewReader("id,date,active_energy\n"), 0)
+	assert.EqualError(t, err, "missing required columns in CSV header: meter_id")
+}
+
+func TestValidateConsumption(t *testing.T) {
+	date := time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		consumption model.Consumption
+		expectedErr string
+	}{
+		{name: "Valid", consumption: model.Consumption{ID: "a", MeterID: 1, Date: date, ActiveEnergy: 1}},
+		{name: "Missing ID", consumption: model.Consumption{MeterID: 1, Date: date}, expectedErr: "id is required"},
+		{name: "Invalid meter", consumption: model.Consumption{ID: "a", Date: date}, expectedErr: "invalid meter_id: 0"},
+		{name: "Missing date", consumption: model.Consumption{ID: "a", MeterID: 1}, expectedErr: "date is required"},
+		{name: "Negative energy", consumption: model.Consumption{ID: "a", MeterID: 1, Date: date, ExportedEnergy: -2}, expectedErr: "invalid exported_energy: -2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConsumption(tt.consumption)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
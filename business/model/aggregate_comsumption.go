@@ -1,7 +1,10 @@
 package model
 
+import "time"
+
 type AggregatedConsumption struct {
-	Period             []string  `json:"period"`
+	Start              time.Time `json:"start"`
+	Period             string    `json:"period"`
 	ActiveEnergy       []float64 `json:"active"`
 	ReactiveInductive  []float64 `json:"reactive_inductive"`
 	ReactiveCapacitive []float64 `json:"reactive_capacitive"`
@@ -3,11 +3,11 @@ package model
 import "time"
 
 type Consumption struct {
-	ID                 string    `gorm:"primaryKey"`
-	MeterID            int       `gorm:"index"`
-	Date               time.Time `gorm:"index"`
-	ActiveEnergy       float64
-	ReactiveInductive  float64
-	ReactiveCapacitive float64
-	ExportedEnergy     float64
+	ID                 string    `gorm:"primaryKey" json:"id"`
+	MeterID            int       `gorm:"index" json:"meter_id"`
+	Date               time.Time `gorm:"index" json:"date"`
+	ActiveEnergy       float64   `json:"active_energy"`
+	ReactiveInductive  float64   `json:"reactive_inductive"`
+	ReactiveCapacitive float64   `json:"reactive_capacitive"`
+	ExportedEnergy     float64   `json:"exported_energy"`
 }
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Tipos de medidor según cómo reportan la energía.
+const (
+	MeterTypeConsumer = "consumer"
+	MeterTypeProsumer = "prosumer"
+)
+
+// Estados de un medidor.
+const (
+	MeterStatusActive   = "active"
+	MeterStatusInactive = "inactive"
+	MeterStatusRemoved  = "removed"
+)
+
+// Meter describe un medidor y cómo interpretar sus lecturas.
+type Meter struct {
+	ID     int    `gorm:"primaryKey" json:"id"`
+	Serial string `gorm:"uniqueIndex;not null" json:"serial"`
+	Type   string `gorm:"not null" json:"type"`
+	// Cumulative indica que las lecturas son registros acumulados y no consumos por intervalo.
+	Cumulative bool `gorm:"not null" json:"cumulative"`
+	// Multiplier es la relación de transformación (CT) por la que se multiplican las lecturas.
+	Multiplier  float64    `gorm:"not null" json:"multiplier"`
+	Timezone    string     `gorm:"not null" json:"timezone"`
+	Tariff      string     `json:"tariff"`
+	InstalledAt *time.Time `json:"installed_at"`
+	RemovedAt   *time.Time `json:"removed_at"`
+	Status      string     `gorm:"not null" json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
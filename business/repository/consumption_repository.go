@@ -1,29 +1,290 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/aggregate"
 	"github.com/SaidHernandez/bia-comsumtion/business/model"
-	"github.com/SaidHernandez/bia-comsumtion/infraestructure/db"
+	"gorm.io/gorm"
+)
+
+// Columnas de energía de la tabla de consumos.
+const (
+	ColumnActiveEnergy       = "active_energy"
+	ColumnReactiveInductive  = "reactive_inductive"
+	ColumnReactiveCapacitive = "reactive_capacitive"
+	ColumnExportedEnergy     = "exported_energy"
 )
 
+// EnergyColumns lista las columnas de energía en el orden de la tabla.
+var EnergyColumns = []string{
+	ColumnActiveEnergy,
+	ColumnReactiveInductive,
+	ColumnReactiveCapacitive,
+	ColumnExportedEnergy,
+}
+
 type ConsumptionRepositoryInterface interface {
-	GetConsumptionByFilters(meterID int, startDate, endDate string) ([]model.Consumption, error)
+	// GetConsumptionByFilters carga los consumos del medidor con fecha en [start, end);
+	// fields limita las columnas de energía a cargar (todas si está vacío).
+	GetConsumptionByFilters(ctx context.Context, meterID int, start, end time.Time, fields []string) ([]model.Consumption, error)
+	// GetConsumptionByMeters carga en una sola consulta los consumos de varios
+	// medidores con fecha en [start, end), ordenados por medidor y fecha.
+	GetConsumptionByMeters(ctx context.Context, meterIDs []int, start, end time.Time, fields []string) ([]model.Consumption, error)
+	// GetLastReadingsBefore devuelve, por medidor, la última lectura con fecha
+	// anterior a before; los medidores sin lecturas previas se omiten.
+	GetLastReadingsBefore(ctx context.Context, meterIDs []int, before time.Time, fields []string) ([]model.Consumption, error)
+}
+
+// Funciones de agregación que la base de datos puede aplicar por periodo.
+const (
+	AggregateSum   = "SUM"
+	AggregateMean  = "AVG"
+	AggregateMin   = "MIN"
+	AggregateMax   = "MAX"
+	AggregateCount = "COUNT"
+)
+
+// AggregatingRepositoryInterface lo implementan los repositorios que pueden
+// agrupar los consumos por periodo directamente en la base de datos.
+type AggregatingRepositoryInterface interface {
+	// GetAggregatedConsumption devuelve una fila por medidor y periodo, con la
+	// fecha truncada a unit (en UTC) y cada columna agregada con function.
+	GetAggregatedConsumption(ctx context.Context, meterIDs []int, start, end time.Time, unit, function string, fields []string) ([]model.Consumption, error)
+}
+
+// ReadingCursor identifica la última lectura de una página; la siguiente
+// página empieza después de ella en el orden (date, id).
+type ReadingCursor struct {
+	Date time.Time
+	ID   string
+}
+
+// ReadingRepositoryInterface lo implementan los repositorios que devuelven las
+// lecturas crudas de un medidor paginadas por cursor y que las guardan.
+type ReadingRepositoryInterface interface {
+	// GetReadings devuelve hasta limit lecturas del medidor con fecha en
+	// [start, end), ordenadas por fecha e id y posteriores a after si no es nil.
+	GetReadings(ctx context.Context, meterID int, start, end time.Time, after *ReadingCursor, limit int) ([]model.Consumption, error)
+	// SaveReadings guarda todas las lecturas o ninguna si la política de
+	// duplicados rechaza alguna.
+	SaveReadings(ctx context.Context, readings []model.Consumption) (*SaveResult, error)
+	// ImportReadings guarda las lecturas aceptadas aunque la política de
+	// duplicados rechace otras.
+	ImportReadings(ctx context.Context, readings []model.Consumption) (*SaveResult, error)
 }
 
-type ConsumptionRepository struct{}
+// meterBatchSize limita los IDs por consulta para no superar el máximo de
+// parámetros de SQLite.
+const meterBatchSize = 500
+
+type ConsumptionRepository struct {
+	db     *gorm.DB
+	policy DuplicatePolicy
+}
 
-func NewConsumptionRepository() *ConsumptionRepository {
-	return &ConsumptionRepository{}
+func NewConsumptionRepository(db *gorm.DB) *ConsumptionRepository {
+	return &ConsumptionRepository{db: db, policy: KeepFirst}
 }
 
-func (a *ConsumptionRepository) GetConsumptionByFilters(meterID int, startDate, endDate string) ([]model.Consumption, error) {
+func (a *ConsumptionRepository) GetConsumptionByFilters(ctx context.Context, meterID int, start, end time.Time, fields []string) ([]model.Consumption, error) {
 	var consumptions []model.Consumption
-	query := db.DB
+	query := a.db.WithContext(ctx)
+	if len(fields) > 0 {
+		query = query.Select(append([]string{"id", "meter_id", "date"}, fields...))
+	}
 	if meterID != 0 {
 		query = query.Where("meter_id = ?", meterID)
 	}
-	if startDate != "" && endDate != "" {
-		query = query.Where("date BETWEEN ? AND ?", startDate, endDate)
-	}
+	query = withDateRange(query, start, end)
 	result := query.Find(&consumptions)
 	return consumptions, result.Error
 }
+
+func (a *ConsumptionRepository) GetConsumptionByMeters(ctx context.Context, meterIDs []int, start, end time.Time, fields []string) ([]model.Consumption, error) {
+	var consumptions []model.Consumption
+	for _, ids := range meterBatches(meterIDs) {
+		var batch []model.Consumption
+		query := a.db.WithContext(ctx).Where("meter_id IN ?", ids)
+		if len(fields) > 0 {
+			query = query.Select(append([]string{"id", "meter_id", "date"}, fields...))
+		}
+		query = withDateRange(query, start, end)
+		if err := query.Order("meter_id, date").Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		consumptions = append(consumptions, batch...)
+	}
+
+	return consumptions, nil
+}
+
+func (a *ConsumptionRepository) GetLastReadingsBefore(ctx context.Context, meterIDs []int, before time.Time, fields []string) ([]model.Consumption, error) {
+	columns := []string{"c.id", "c.meter_id", "c.date"}
+	if len(fields) == 0 {
+		fields = EnergyColumns
+	}
+	for _, field := range fields {
+		columns = append(columns, "c."+field)
+	}
+
+	var readings []model.Consumption
+	for _, ids := range meterBatches(meterIDs) {
+		latest := a.db.Model(&model.Consumption{}).
+			Select("meter_id, MAX(date) AS date").
+			Where("meter_id IN ? AND date < ?", ids, before.UTC()).
+			Group("meter_id")
+
+		// El índice único (meter_id, date) garantiza una sola lectura por medidor
+		// en la última fecha.
+		var batch []model.Consumption
+		err := a.db.WithContext(ctx).Table("consumptions AS c").
+			Select(columns).
+			Joins("JOIN (?) AS latest ON latest.meter_id = c.meter_id AND latest.date = c.date", latest).
+			Order("c.meter_id").
+			Find(&batch).Error
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, batch...)
+	}
+	return readings, nil
+}
+
+func (a *ConsumptionRepository) GetReadings(ctx context.Context, meterID int, start, end time.Time, after *ReadingCursor, limit int) ([]model.Consumption, error) {
+	var readings []model.Consumption
+	query := withDateRange(a.db.WithContext(ctx).Where("meter_id = ?", meterID), start, end)
+	if after != nil {
+		afterDate := after.Date.UTC()
+		query = query.Where("(date > ? OR (date = ? AND id > ?))", afterDate, afterDate, after.ID)
+	}
+	result := query.Order("date, id").Limit(limit).Find(&readings)
+	return readings, result.Error
+}
+
+// bucketFormats son los formatos de strftime que truncan la fecha en SQLite.
+var bucketFormats = map[string]string{
+	aggregate.UnitHour:  "%Y-%m-%d %H:00:00",
+	aggregate.UnitDay:   "%Y-%m-%d 00:00:00",
+	aggregate.UnitMonth: "%Y-%m-01 00:00:00",
+	aggregate.UnitYear:  "%Y-01-01 00:00:00",
+}
+
+var aggregateFunctions = map[string]bool{
+	AggregateSum:   true,
+	AggregateMean:  true,
+	AggregateMin:   true,
+	AggregateMax:   true,
+	AggregateCount: true,
+}
+
+const bucketLayout = "2006-01-02 15:04:05"
+
+// aggregatedRow es una fila agrupada por medidor y periodo.
+type aggregatedRow struct {
+	MeterID            int
+	Bucket             string
+	ActiveEnergy       float64
+	ReactiveInductive  float64
+	ReactiveCapacitive float64
+	ExportedEnergy     float64
+}
+
+func (a *ConsumptionRepository) GetAggregatedConsumption(ctx context.Context, meterIDs []int, start, end time.Time, unit, function string, fields []string) ([]model.Consumption, error) {
+	if !aggregateFunctions[function] {
+		return nil, fmt.Errorf("unsupported aggregate function: %s", function)
+	}
+	bucket, err := bucketExpression(a.db.Dialector.Name(), unit)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		fields = EnergyColumns
+	}
+
+	columns := []string{"meter_id", bucket + " AS bucket"}
+	for _, field := range fields {
+		columns = append(columns, fmt.Sprintf("%s(%s) AS %s", function, field, field))
+	}
+
+	var consumptions []model.Consumption
+	for _, ids := range meterBatches(meterIDs) {
+		var rows []aggregatedRow
+		query := a.db.WithContext(ctx).Model(&model.Consumption{}).
+			Select(strings.Join(columns, ", ")).
+			Where("meter_id IN ?", ids)
+		query = withDateRange(query, start, end)
+		if err := query.Group("meter_id, bucket").Order("meter_id, bucket").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			date, err := time.ParseInLocation(bucketLayout, row.Bucket, time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bucket %q: %w", row.Bucket, err)
+			}
+			consumptions = append(consumptions, model.Consumption{
+				MeterID:            row.MeterID,
+				Date:               date,
+				ActiveEnergy:       row.ActiveEnergy,
+				ReactiveInductive:  row.ReactiveInductive,
+				ReactiveCapacitive: row.ReactiveCapacitive,
+				ExportedEnergy:     row.ExportedEnergy,
+			})
+		}
+	}
+
+	return consumptions, nil
+}
+
+// bucketExpression devuelve la expresión SQL que trunca la fecha a unit en UTC
+// y la formatea con bucketLayout.
+func bucketExpression(dialect, unit string) (string, error) {
+	format, exists := bucketFormats[unit]
+	if !exists {
+		return "", fmt.Errorf("unsupported bucket unit: %s", unit)
+	}
+
+	switch dialect {
+	case "sqlite":
+		return fmt.Sprintf("strftime('%s', date)", format), nil
+	case "postgres":
+		return fmt.Sprintf("to_char(date_trunc('%s', date AT TIME ZONE 'UTC'), 'YYYY-MM-DD HH24:MI:SS')", unit), nil
+	default:
+		return "", fmt.Errorf("unsupported dialect for aggregation: %s", dialect)
+	}
+}
+
+// withDateRange filtra las fechas en [start, end); un límite en cero no se aplica.
+// Los límites se pasan en UTC, la zona en que se guardan las lecturas, para que
+// la comparación de las fechas guardadas como texto en SQLite sea correcta.
+func withDateRange(query *gorm.DB, start, end time.Time) *gorm.DB {
+	if !start.IsZero() {
+		query = query.Where("date >= ?", start.UTC())
+	}
+	if !end.IsZero() {
+		query = query.Where("date < ?", end.UTC())
+	}
+	return query
+}
+
+// meterBatches ordena los IDs y los divide en lotes de meterBatchSize.
+func meterBatches(meterIDs []int) [][]int {
+	sortedIDs := make([]int, len(meterIDs))
+	copy(sortedIDs, meterIDs)
+	sort.Ints(sortedIDs)
+
+	var batches [][]int
+	for start := 0; start < len(sortedIDs); start += meterBatchSize {
+		end := start + meterBatchSize
+		if end > len(sortedIDs) {
+			end = len(sortedIDs)
+		}
+		batches = append(batches, sortedIDs[start:end])
+	}
+	return batches
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/aggregate"
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestRepository abre una base SQLite en memoria con los consumos indicados.
+func setupTestRepository(t *testing.T, consumptions []model.Consumption) *ConsumptionRepository {
+	conn := dbtest.Open(t)
+	if len(consumptions) > 0 {
+		require.NoError(t, conn.Create(consumptions).Error)
+	}
+	return NewConsumptionRepository(conn)
+}
+
+func consumptionIDs(consumptions []model.Consumption) []string {
+	ids := make([]string, len(consumptions))
+	for i, consumption := range consumptions {
+		ids[i] = consumption.ID
+	}
+	return ids
+}
+
+func TestConsumptionRepository_GetConsumptionByMeters(t *testing.T) {
+	bogota, _ := time.LoadLocation("America/Bogota")
+
+	repository := setupTestRepository(t, []model.Consumption{
+		{ID: "before-start", MeterID: 1, ActiveEnergy: 1, Date: time.Date(2023, 6, 30, 23, 59, 59, 0, time.UTC)},
+		{ID: "at-start", MeterID: 1, ActiveEnergy: 2, Date: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "end-day-morning", MeterID: 1, ActiveEnergy: 3, Date: time.Date(2023, 7, 31, 10, 0, 0, 0, time.UTC)},
+		{ID: "end-day-last-second", MeterID: 1, ActiveEnergy: 4, Date: time.Date(2023, 7, 31, 23, 59, 59, 500, time.UTC)},
+		{ID: "after-end", MeterID: 1, ActiveEnergy: 5, Date: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "other-meter", MeterID: 2, ActiveEnergy: 6, Date: time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: "unrequested-meter", MeterID: 3, ActiveEnergy: 7, Date: time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC)},
+	})
+
+	tests := []struct {
+		name        string
+		meterIDs    []int
+		start       time.Time
+		end         time.Time
+		fields      []string
+		expectedIDs []string
+	}{
+		{
+			name:        "Readings on the whole end day are included",
+			meterIDs:    []int{1},
+			start:       time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"at-start", "end-day-morning", "end-day-last-second"},
+		},
+		{
+			name:        "Bounds in another timezone are compared as instants",
+			meterIDs:    []int{1},
+			start:       time.Date(2023, 6, 30, 19, 0, 0, 0, bogota),
+			end:         time.Date(2023, 7, 31, 19, 0, 0, 0, bogota),
+			expectedIDs: []string{"at-start", "end-day-morning", "end-day-last-second"},
+		},
+		{
+			name:        "Results are ordered by meter and date",
+			meterIDs:    []int{2, 1},
+			start:       time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+			end:         time.Date(2023, 7, 31, 0, 0, 0, 0, time.UTC),
+			expectedIDs: []string{"at-start", "other-meter"},
+		},
+		{
+			name:        "Zero bounds load every reading",
+			meterIDs:    []int{1},
+			expectedIDs: []string{"before-start", "at-start", "end-day-morning", "end-day-last-second", "after-end"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumptions, err := repository.GetConsumptionByMeters(context.Background(), tt.meterIDs, tt.start, tt.end, tt.fields)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedIDs, consumptionIDs(consumptions))
+		})
+	}
+}
+
+func TestConsumptionRepository_GetConsumptionByFilters(t *testing.T) {
+	repository := setupTestRepository(t, []model.Consumption{
+		{ID: "1", MeterID: 1, ActiveEnergy: 10, ExportedEnergy: 4, Date: time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)},
+		{ID: "2", MeterID: 1, ActiveEnergy: 20, ExportedEnergy: 5, Date: time.Date(2023, 7, 4, 23, 45, 0, 0, time.UTC)},
+		{ID: "3", MeterID: 1, ActiveEnergy: 30, ExportedEnergy: 6, Date: time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)},
+	})
+
+	consumptions, err := repository.GetConsumptionByFilters(context.Background(), 1,
+		time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC), []string{ColumnActiveEnergy})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, consumptionIDs(consumptions))
+	for _, consumption := range consumptions {
+		assert.Zero(t, consumption.ExportedEnergy)
+	}
+}
+
+func TestConsumptionRepository_GetAggregatedConsumption(t *testing.T) {
+	repository := setupTestRepository(t, []model.Consumption{
+		{ID: "1", MeterID: 1, ActiveEnergy: 10, Date: time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)},
+		{ID: "2", MeterID: 1, ActiveEnergy: 20, Date: time.Date(2023, 7, 4, 23, 45, 0, 0, time.UTC)},
+		{ID: "3", MeterID: 1, ActiveEnergy: 30, Date: time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{ID: "4", MeterID: 1, ActiveEnergy: 40, Date: time.Date(2023, 7, 6, 0, 0, 0, 0, time.UTC)},
+	})
+
+	consumptions, err := repository.GetAggregatedConsumption(context.Background(), []int{1},
+		time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), time.Date(2023, 7, 6, 0, 0, 0, 0, time.UTC),
+		aggregate.UnitDay, AggregateSum, []string{ColumnActiveEnergy})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Consumption{
+		{MeterID: 1, ActiveEnergy: 30, Date: time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)},
+		{MeterID: 1, ActiveEnergy: 30, Date: time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)},
+	}, consumptions)
+
+	_, err = repository.GetAggregatedConsumption(context.Background(), []int{1}, time.Time{}, time.Time{}, aggregate.UnitDay, "MEDIAN", nil)
+	assert.EqualError(t, err, "unsupported aggregate function: MEDIAN")
+}
+
+func TestConsumptionRepository_GetAggregatedConsumption_Units(t *testing.T) {
+	repository := setupTestRepository(t, []model.Consumption{
+		{ID: "1", MeterID: 1, ActiveEnergy: 10, Date: time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)},
+		{ID: "2", MeterID: 1, ActiveEnergy: 20, Date: time.Date(2023, 7, 4, 10, 45, 0, 0, time.UTC)},
+		{ID: "3", MeterID: 1, ActiveEnergy: 30, Date: time.Date(2023, 7, 31, 23, 59, 59, 0, time.UTC)},
+		{ID: "4", MeterID: 1, ActiveEnergy: 40, Date: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "5", MeterID: 1, ActiveEnergy: 50, Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "6", MeterID: 2, ActiveEnergy: 60, Date: time.Date(2023, 7, 4, 10, 30, 0, 0, time.UTC)},
+	})
+
+	tests := []struct {
+		name                 string
+		unit                 string
+		function             string
+		expectedConsumptions []model.Consumption
+	}{
+		{
+			name:     "Count per hour",
+			unit:     aggregate.UnitHour,
+			function: AggregateCount,
+			expectedConsumptions: []model.Consumption{
+				{MeterID: 1, ActiveEnergy: 2, Date: time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)},
+				{MeterID: 1, ActiveEnergy: 1, Date: time.Date(2023, 7, 31, 23, 0, 0, 0, time.UTC)},
+				{MeterID: 1, ActiveEnergy: 1, Date: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)},
+				{MeterID: 1, ActiveEnergy: 1, Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{MeterID: 2, ActiveEnergy: 1, Date: time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:     "Max per month",
+			unit:     aggregate.UnitMonth,
+			function: AggregateMax,
+			expectedConsumptions: []model.Consumption{
+				{MeterID: 1, ActiveEnergy: 30, Date: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+				{MeterID: 1, ActiveEnergy: 40, Date: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)},
+				{MeterID: 1, ActiveEnergy: 50, Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{MeterID: 2, ActiveEnergy: 60, Date: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:     "Sum per year",
+			unit:     aggregate.UnitYear,
+			function: AggregateSum,
+			expectedConsumptions: []model.Consumption{
+				{MeterID: 1, ActiveEnergy: 100, Date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{MeterID: 1, ActiveEnergy: 50, Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{MeterID: 2, ActiveEnergy: 60, Date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumptions, err := repository.GetAggregatedConsumption(context.Background(), []int{2, 1},
+				time.Time{}, time.Time{}, tt.unit, tt.function, []string{ColumnActiveEnergy})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedConsumptions, consumptions)
+		})
+	}
+}
+
+func TestBucketExpression(t *testing.T) {
+	tests := []struct {
+		name               string
+		dialect            string
+		unit               string
+		expectedExpression string
+		expectedErr        string
+	}{
+		{name: "SQLite hour", dialect: "sqlite", unit: aggregate.UnitHour, expectedExpression: "strftime('%Y-%m-%d %H:00:00', date)"},
+		{name: "SQLite day", dialect: "sqlite", unit: aggregate.UnitDay, expectedExpression: "strftime('%Y-%m-%d 00:00:00', date)"},
+		{name: "SQLite month", dialect: "sqlite", unit: aggregate.UnitMonth, expectedExpression: "strftime('%Y-%m-01 00:00:00', date)"},
+		{name: "SQLite year", dialect: "sqlite", unit: aggregate.UnitYear, expectedExpression: "strftime('%Y-01-01 00:00:00', date)"},
+		{name: "Postgres hour", dialect: "postgres", unit: aggregate.UnitHour, expectedExpression: "to_char(date_trunc('hour', date AT TIME ZONE 'UTC'), 'YYYY-MM-DD HH24:MI:SS')"},
+		{name: "Postgres day", dialect: "postgres", unit: aggregate.UnitDay, expectedExpression: "to_char(date_trunc('day', date AT TIME ZONE 'UTC'), 'YYYY-MM-DD HH24:MI:SS')"},
+		{name: "Postgres month", dialect: "postgres", unit: aggregate.UnitMonth, expectedExpression: "to_char(date_trunc('month', date AT TIME ZONE 'UTC'), 'YYYY-MM-DD HH24:MI:SS')"},
+		{name: "Postgres year", dialect: "postgres", unit: aggregate.UnitYear, expectedExpression: "to_char(date_trunc('year', date AT TIME ZONE 'UTC'), 'YYYY-MM-DD HH24:MI:SS')"},
+		{name: "Unsupported unit", dialect: "sqlite", unit: "week", expectedErr: "unsupported bucket unit: week"},
+		{name: "Unsupported dialect", dialect: "mysql", unit: aggregate.UnitDay, expectedErr: "unsupported dialect for aggregation: mysql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := bucketExpression(tt.dialect, tt.unit)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedExpression, expression)
+		})
+	}
+}
+
+func TestConsumptionRepository_GetReadings(t *testing.T) {
+	date1 := time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)
+	date2 := time.Date(2023, 7, 4, 10, 15, 0, 0, time.UTC)
+	repository := setupTestRepository(t, []model.Consumption{
+		{ID: "a", MeterID: 1, Date: date2},
+		{ID: "c", MeterID: 1, Date: date1},
+		{ID: "b", MeterID: 1, Date: date2.Add(15 * time.Minute)},
+		{ID: "d", MeterID: 1, Date: time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{ID: "e", MeterID: 2, Date: date1},
+	})
+	start := time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)
+
+	firstPage, err := repository.GetReadings(context.Background(), 1, start, end, nil, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, consumptionIDs(firstPage))
+
+	last := firstPage[len(firstPage)-1]
+	secondPage, err := repository.GetReadings(context.Background(), 1, start, end, &ReadingCursor{Date: last.Date, ID: last.ID}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, consumptionIDs(secondPage))
+}
+
+func TestConsumptionRepository_GetLastReadingsBefore(t *testing.T) {
+	start := time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)
+	repository := setupTestRepository(t, []model.Consumption{
+		{ID: "old", MeterID: 1, ActiveEnergy: 1, Date: start.Add(-48 * time.Hour)},
+		{ID: "last", MeterID: 1, ActiveEnergy: 2, ReactiveInductive: 3, Date: start.Add(-time.Minute)},
+		{ID: "at-start", MeterID: 1, ActiveEnergy: 3, Date: start},
+		{ID: "other-meter", MeterID: 2, ActiveEnergy: 4, Date: start.Add(-time.Hour)},
+		{ID: "only-after", MeterID: 3, ActiveEnergy: 5, Date: start.Add(time.Hour)},
+	})
+
+	readings, err := repository.GetLastReadingsBefore(context.Background(), []int{1, 2, 3}, start, []string{ColumnActiveEnergy})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"last", "other-meter"}, consumptionIDs(readings))
+	assert.Equal(t, 2.0, readings[0].ActiveEnergy)
+	assert.Zero(t, readings[0].ReactiveInductive)
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"gorm.io/gorm"
+)
+
+// ErrMeterNotFound indica que no existe un medidor con el ID pedido.
+var ErrMeterNotFound = errors.New("meter not found")
+
+// ErrMeterExists indica que ya existe un medidor con el mismo ID o serial.
+var ErrMeterExists = errors.New("meter already exists")
+
+type MeterRepositoryInterface interface {
+	GetMeter(ctx context.Context, meterID int) (*model.Meter, error)
+	// GetMetersByIDs devuelve los medidores existentes entre los IDs pedidos,
+	// ordenados por ID; los IDs desconocidos se omiten.
+	GetMetersByIDs(ctx context.Context, meterIDs []int) ([]model.Meter, error)
+	ListMeters(ctx context.Context) ([]model.Meter, error)
+	CreateMeter(ctx context.Context, meter *model.Meter) error
+	UpdateMeter(ctx context.Context, meter *model.Meter) error
+	DeleteMeter(ctx context.Context, meterID int) error
+}
+
+type MeterRepository struct {
+	db *gorm.DB
+}
+
+func NewMeterRepository(db *gorm.DB) *MeterRepository {
+	return &MeterRepository{db: db}
+}
+
+func (a *MeterRepository) GetMeter(ctx context.Context, meterID int) (*model.Meter, error) {
+	var meter model.Meter
+	err := a.db.WithContext(ctx).First(&meter, meterID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMeterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &meter, nil
+}
+
+func (a *MeterRepository) GetMetersByIDs(ctx context.Context, meterIDs []int) ([]model.Meter, error) {
+	var meters []model.Meter
+	for _, ids := range meterBatches(meterIDs) {
+		var batch []model.Meter
+		if err := a.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		meters = append(meters, batch...)
+	}
+	return meters, nil
+}
+
+func (a *MeterRepository) ListMeters(ctx context.Context) ([]model.Meter, error) {
+	var meters []model.Meter
+	result := a.db.WithContext(ctx).Order("id").Find(&meters)
+	return meters, result.Error
+}
+
+func (a *MeterRepository) CreateMeter(ctx context.Context, meter *model.Meter) error {
+	return meterWriteError(a.db.WithContext(ctx).Create(meter).Error)
+}
+
+// UpdateMeter reemplaza todos los campos del medidor. Falla con ErrMeterNotFound
+// si no existe.
+func (a *MeterRepository) UpdateMeter(ctx context.Context, meter *model.Meter) error {
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.Meter
+		err := tx.Select("id", "created_at").First(&existing, meter.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMeterNotFound
+		}
+		if err != nil {
+			return err
+		}
+		meter.CreatedAt = existing.CreatedAt
+		return meterWriteError(tx.Save(meter).Error)
+	})
+}
+
+func (a *MeterRepository) DeleteMeter(ctx context.Context, meterID int) error {
+	result := a.db.WithContext(ctx).Delete(&model.Meter{}, meterID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMeterNotFound
+	}
+	return nil
+}
+
+func meterWriteError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrMeterExists
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeterRepository_CRUD(t *testing.T) {
+	repository := NewMeterRepository(dbtest.Open(t))
+	ctx := context.Background()
+
+	meter := &model.Meter{ID: 2, Serial: "SN-2", Type: model.MeterTypeConsumer, Multiplier: 1, Timezone: "UTC", Status: model.MeterStatusActive}
+	require.NoError(t, repository.CreateMeter(ctx, meter))
+	require.NoError(t, repository.CreateMeter(ctx, &model.Meter{ID: 1, Serial: "SN-1", Type: model.MeterTypeProsumer, Cumulative: true, Multiplier: 40, Timezone: "America/Bogota", Status: model.MeterStatusActive}))
+
+	assert.ErrorIs(t, repository.CreateMeter(ctx, &model.Meter{ID: 3, Serial: "SN-2", Type: model.MeterTypeConsumer, Multiplier: 1, Timezone: "UTC", Status: model.MeterStatusActive}), ErrMeterExists)
+
+	found, err := repository.GetMeter(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, found.Cumulative)
+	assert.Equal(t, 40.0, found.Multiplier)
+
+	_, err = repository.GetMeter(ctx, 9)
+	assert.ErrorIs(t, err, ErrMeterNotFound)
+
+	meters, err := repository.GetMetersByIDs(ctx, []int{2, 9, 1})
+	assert.NoError(t, err)
+	require.Len(t, meters, 2)
+	assert.Equal(t, 1, meters[0].ID)
+	assert.Equal(t, 2, meters[1].ID)
+
+	createdAt := meter.CreatedAt
+	updated := &model.Meter{ID: 2, Serial: "SN-2B", Type: model.MeterTypeConsumer, Multiplier: 1, Timezone: "UTC", Status: model.MeterStatusRemoved}
+	assert.NoError(t, repository.UpdateMeter(ctx, updated))
+	assert.True(t, createdAt.Equal(updated.CreatedAt))
+	found, err = repository.GetMeter(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "SN-2B", found.Serial)
+	assert.Equal(t, model.MeterStatusRemoved, found.Status)
+
+	assert.ErrorIs(t, repository.UpdateMeter(ctx, &model.Meter{ID: 9, Serial: "SN-9"}), ErrMeterNotFound)
+
+	assert.NoError(t, repository.DeleteMeter(ctx, 2))
+	assert.ErrorIs(t, repository.DeleteMeter(ctx, 2), ErrMeterNotFound)
+
+	meters, err = repository.ListMeters(ctx)
+	assert.NoError(t, err)
+	require.Len(t, meters, 1)
+	assert.Equal(t, "SN-1", meters[0].Serial)
+}
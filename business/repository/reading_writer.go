@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DuplicatePolicy indica qué hacer con una lectura de un medidor y fecha que ya
+// existe con otro ID.
+type DuplicatePolicy string
+
+const (
+	// KeepFirst conserva la lectura guardada u ocurrida antes y omite la nueva.
+	KeepFirst DuplicatePolicy = "keep_first"
+	// KeepLast reemplaza la lectura anterior por la nueva.
+	KeepLast DuplicatePolicy = "keep_last"
+	// RejectDuplicates rechaza la lectura nueva y la incluye en el resultado.
+	RejectDuplicates DuplicatePolicy = "reject"
+)
+
+// ParseDuplicatePolicy valida el nombre de la política.
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(name); policy {
+	case KeepFirst, KeepLast, RejectDuplicates:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid duplicate policy: %s (use %s, %s or %s)", name, KeepFirst, KeepLast, RejectDuplicates)
+	}
+}
+
+// ErrDuplicateReading indica que otra escritura guardó al mismo tiempo una
+// lectura del mismo medidor y fecha; la operación puede reintentarse.
+var ErrDuplicateReading = errors.New("duplicate reading for meter and date")
+
+// ReadingReject describe una lectura que la política de duplicados rechazó;
+// Index es su posición en las lecturas recibidas.
+type ReadingReject struct {
+	Index  int
+	Reason string
+}
+
+// SaveResult resume una escritura de lecturas.
+type SaveResult struct {
+	Inserted int
+	Updated  int
+	// Skipped cuenta las lecturas descartadas en favor de otra con el mismo ID
+	// o el mismo medidor y fecha.
+	Skipped int
+	Rejects []ReadingReject
+}
+
+// readingBatchSize limita las filas por INSERT para no superar el máximo de
+// parámetros de SQLite.
+const readingBatchSize = 500
+
+// SetDuplicatePolicy cambia la política de duplicados; por defecto es KeepFirst.
+func (a *ConsumptionRepository) SetDuplicatePolicy(policy DuplicatePolicy) {
+	a.policy = policy
+}
+
+// SaveReadings guarda las lecturas en una sola transacción. Las de un ID ya
+// guardado se actualizan y las de un medidor y fecha ya guardados con otro ID
+// se resuelven con la política de duplicados. Si la política rechaza alguna no
+// se guarda ninguna y el resultado sólo trae los rechazos.
+func (a *ConsumptionRepository) SaveReadings(ctx context.Context, readings []model.Consumption) (*SaveResult, error) {
+	return a.saveReadings(ctx, readings, false)
+}
+
+// ImportReadings guarda las lecturas como SaveReadings, pero conserva las
+// aceptadas aunque la política rechace otras.
+func (a *ConsumptionRepository) ImportReadings(ctx context.Context, readings []model.Consumption) (*SaveResult, error) {
+	return a.saveReadings(ctx, readings, true)
+}
+
+// errRejected revierte SaveReadings cuando la política rechaza alguna lectura.
+var errRejected = errors.New("readings rejected")
+
+func (a *ConsumptionRepository) saveReadings(ctx context.Context, readings []model.Consumption, partial bool) (*SaveResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &SaveResult{}
+	kept := a.dedupe(readings, result)
+	err := a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := a.save(tx, readings, kept, result); err != nil {
+			return err
+		}
+		if len(result.Rejects) > 0 && !partial {
+			return errRejected
+		}
+		return nil
+	})
+	if errors.Is(err, errRejected) {
+		return &SaveResult{Rejects: result.Rejects}, nil
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, fmt.Errorf("%w: %v", ErrDuplicateReading, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readingKey identifica una lectura por medidor y fecha.
+type readingKey struct {
+	meterID int
+	date    int64
+}
+
+func keyOf(consumption model.Consumption) readingKey {
+	return readingKey{meterID: consumption.MeterID, date: consumption.Date.UnixNano()}
+}
+
+// dedupe resuelve los duplicados entre las lecturas recibidas y devuelve las
+// posiciones de las que quedan. Si un ID se repite gana la última versión; si
+// se repite el medidor y la fecha con otro ID se aplica la política.
+func (a *ConsumptionRepository) dedupe(readings []model.Consumption, result *SaveResult) []int {
+	kept := make([]int, 0, len(readings))
+	byID := make(map[string]int)
+	byKey := make(map[readingKey]int)
+	drop := func(position int) {
+		previous := readings[kept[position]]
+		delete(byID, previous.ID)
+		if current, ok := byKey[keyOf(previous)]; ok && current == position {
+			delete(byKey, keyOf(previous))
+		}
+		kept[position] = -1
+		result.Skipped++
+	}
+
+	for index, consumption := range readings {
+		if position, ok := byID[consumption.ID]; ok {
+			drop(position)
+		}
+		if position, ok := byKey[keyOf(consumption)]; ok {
+			switch a.policy {
+			case KeepLast:
+				drop(position)
+			case RejectDuplicates:
+				result.reject(index, consumption, readings[kept[position]].ID)
+				continue
+			default:
+				result.Skipped++
+				continue
+			}
+		}
+		byID[consumption.ID] = len(kept)
+		byKey[keyOf(consumption)] = len(kept)
+		kept = append(kept, index)
+	}
+
+	indexes := make([]int, 0, len(kept))
+	for _, index := range kept {
+		if index >= 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+// save aplica la política de duplicados frente a lo ya guardado, guarda las
+// lecturas y registra los medidores que todavía no existen.
+func (a *ConsumptionRepository) save(tx *gorm.DB, readings []model.Consumption, kept []int, result *SaveResult) error {
+	consumptions, replaced, err := a.resolveStored(tx, readings, kept, result)
+	if err != nil {
+		return err
+	}
+	if len(consumptions) == 0 {
+		return nil
+	}
+
+	if len(replaced) > 0 {
+		if err := tx.Delete(&model.Consumption{}, "id IN ?", replaced).Error; err != nil {
+			return fmt.Errorf("failed to replace duplicate records: %w", err)
+		}
+	}
+	if err := upsertReadings(tx, consumptions); err != nil {
+		return err
+	}
+	return registerMeters(tx, consumptions)
+}
+
+// upsertReadings inserta las lecturas y actualiza las que ya existen por ID.
+func upsertReadings(tx *gorm.DB, consumptions []model.Consumption) error {
+	upsert := clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"meter_id", "date", "active_energy", "reactive_inductive", "reactive_capacitive", "exported_energy",
+		}),
+	}
+	if err := tx.Clauses(upsert).CreateInBatches(&consumptions, readingBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to insert records into database: %w", err)
+	}
+	return nil
+}
+
+// resolveStored compara las lecturas con las guardadas. Devuelve las lecturas
+// a guardar y los IDs guardados que la política KeepLast reemplaza.
+func (a *ConsumptionRepository) resolveStored(tx *gorm.DB, readings []model.Consumption, kept []int, result *SaveResult) ([]model.Consumption, []string, error) {
+	if len(kept) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]string, 0, len(kept))
+	batchIDs := make(map[string]bool, len(kept))
+	meterIDs := make([]int, 0, len(kept))
+	meters := make(map[int]bool)
+	start, end := readings[kept[0]].Date, readings[kept[0]].Date
+	for _, index := range kept {
+		consumption := readings[index]
+		ids = append(ids, consumption.ID)
+		batchIDs[consumption.ID] = true
+		if !meters[consumption.MeterID] {
+			meters[consumption.MeterID] = true
+			meterIDs = append(meterIDs, consumption.MeterID)
+		}
+		if consumption.Date.Before(start) {
+			start = consumption.Date
+		}
+		if consumption.Date.After(end) {
+			end = consumption.Date
+		}
+	}
+
+	var existingIDs []string
+	if err := tx.Model(&model.Consumption{}).Where("id IN ?", ids).Pluck("id", &existingIDs).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load existing records: %w", err)
+	}
+	existing := make(map[string]bool, len(existingIDs))
+	for _, id := range existingIDs {
+		existing[id] = true
+	}
+
+	var stored []model.Consumption
+	err := tx.Select("id", "meter_id", "date").
+		Where("meter_id IN ? AND date >= ? AND date <= ?", meterIDs, start, end).
+		Find(&stored).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load existing records: %w", err)
+	}
+	// Las lecturas que se actualizan por ID no cuentan como duplicadas.
+	storedByKey := make(map[readingKey]string, len(stored))
+	for _, consumption := range stored {
+		if !batchIDs[consumption.ID] {
+			storedByKey[keyOf(consumption)] = consumption.ID
+		}
+	}
+
+	var consumptions []model.Consumption
+	var replaced []string
+	for _, index := range kept {
+		consumption := readings[index]
+		if storedID, ok := storedByKey[keyOf(consumption)]; ok {
+			switch a.policy {
+			case KeepLast:
+				replaced = append(replaced, storedID)
+				delete(storedByKey, keyOf(consumption))
+				result.Skipped++
+			case RejectDuplicates:
+				result.reject(index, consumption, storedID)
+				continue
+			default:
+				result.Skipped++
+				continue
+			}
+		}
+
+		if existing[consumption.ID] {
+			result.Updated++
+		} else {
+			result.Inserted++
+		}
+		consumptions = append(consumptions, consumption)
+	}
+	return consumptions, replaced, nil
+}
+
+func (r *SaveResult) reject(index int, consumption model.Consumption, otherID string) {
+	r.Rejects = append(r.Rejects, ReadingReject{
+		Index: index,
+		Reason: fmt.Sprintf("duplicate reading for meter %d at %s (id %s)",
+			consumption.MeterID, consumption.Date.Format(time.RFC3339Nano), otherID),
+	})
+}
+
+// registerMeters da de alta como medidores de consumo los de las lecturas que
+// no estén registrados.
+func registerMeters(tx *gorm.DB, consumptions []model.Consumption) error {
+	registered := make(map[int]bool)
+	for _, consumption := range consumptions {
+		if registered[consumption.MeterID] {
+			continue
+		}
+		registered[consumption.MeterID] = true
+
+		meter := model.Meter{}
+		err := tx.Where(model.Meter{ID: consumption.MeterID}).Attrs(model.Meter{
+			Serial:     strconv.Itoa(consumption.MeterID),
+			Type:       model.MeterTypeConsumer,
+			Multiplier: 1,
+			Timezone:   "UTC",
+			Status:     model.MeterStatusActive,
+		}).FirstOrCreate(&meter).Error
+		if err != nil {
+			return fmt.Errorf("failed to register meter %d: %w", consumption.MeterID, err)
+		}
+	}
+	return nil
+}
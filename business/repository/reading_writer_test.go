@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestConsumptionRepository_SaveReadings(t *testing.T) {
+	date := time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)
+	stored := []model.Consumption{{ID: "stored", MeterID: 1, ActiveEnergy: 1, Date: date}}
+	readings := []model.Consumption{
+		{ID: "new", MeterID: 1, ActiveEnergy: 2, Date: date},
+		{ID: "other", MeterID: 1, ActiveEnergy: 3, Date: date.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name           string
+		policy         DuplicatePolicy
+		partial        bool
+		expectedResult SaveResult
+		expectedIDs    []string
+	}{
+		{
+			name:           "Keep first",
+			policy:         KeepFirst,
+			expectedResult: SaveResult{Inserted: 1, Skipped: 1},
+			expectedIDs:    []string{"other", "stored"},
+		},
+		{
+			name:           "Keep last",
+			policy:         KeepLast,
+			expectedResult: SaveResult{Inserted: 2, Skipped: 1},
+			expectedIDs:    []string{"new", "other"},
+		},
+		{
+			name:   "Reject saves nothing",
+			policy: RejectDuplicates,
+			expectedResult: SaveResult{Rejects: []ReadingReject{
+				{Index: 0, Reason: "duplicate reading for meter 1 at 2023-07-04T10:00:00Z (id stored)"},
+			}},
+			expectedIDs: []string{"stored"},
+		},
+		{
+			name:    "Import keeps the accepted readings",
+			policy:  RejectDuplicates,
+			partial: true,
+			expectedResult: SaveResult{Inserted: 1, Rejects: []ReadingReject{
+				{Index: 0, Reason: "duplicate reading for meter 1 at 2023-07-04T10:00:00Z (id stored)"},
+			}},
+			expectedIDs: []string{"other", "stored"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dbtest.Open(t)
+			require.NoError(t, conn.Create(stored).Error)
+			repository := NewConsumptionRepository(conn)
+			repository.SetDuplicatePolicy(tt.policy)
+
+			save := repository.SaveReadings
+			if tt.partial {
+				save = repository.ImportReadings
+			}
+			result, err := save(context.Background(), readings)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, *result)
+
+			var ids []string
+			require.NoError(t, conn.Model(&model.Consumption{}).Order("id").Pluck("id", &ids).Error)
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestConsumptionRepository_SaveReadings_ConcurrentDuplicate(t *testing.T) {
+	date := time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)
+	conn := dbtest.Open(t)
+	repository := NewConsumptionRepository(conn)
+
+	// Otra escritura guarda la misma lectura después de que la transacción
+	// buscó los duplicados y antes de que inserte.
+	err := conn.Callback().Create().Before("gorm:create").Register("concurrent_write", func(tx *gorm.DB) {
+		if tx.Statement.Table == "consumptions" {
+			tx.Session(&gorm.Session{NewDB: true}).Exec("INSERT INTO consumptions (id, meter_id, date, active_energy) VALUES (?, ?, ?, ?)", "concurrent", 1, date, 1)
+		}
+	})
+	require.NoError(t, err)
+
+	_, err = repository.SaveReadings(context.Background(), []model.Consumption{{ID: "new", MeterID: 1, ActiveEnergy: 2, Date: date}})
+
+	assert.ErrorIs(t, err, ErrDuplicateReading)
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	policy, err := ParseDuplicatePolicy("keep_last")
+	assert.NoError(t, err)
+	assert.Equal(t, KeepLast, policy)
+
+	_, err = ParseDuplicatePolicy("newest")
+	assert.EqualError(t, err, "invalid duplicate policy: newest (use keep_first, keep_last or reject)")
+}
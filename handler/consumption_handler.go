@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,50 +15,64 @@ import (
 // ConsumptionHandler maneja las solicitudes relacionadas con el consumo de energía.
 type ConsumptionHandler struct {
 	service *services.ConsumptionService
+	timeout time.Duration
 }
 
-// NewConsumptionHandler crea una nueva instancia de ConsumptionHandler.
-func NewConsumptionHandler(service *services.ConsumptionService) *ConsumptionHandler {
-	return &ConsumptionHandler{service: service}
+// NewConsumptionHandler crea una nueva instancia de ConsumptionHandler. Si timeout
+// es mayor que cero, cada solicitud se cancela al superar ese tiempo.
+func NewConsumptionHandler(service *services.ConsumptionService, timeout time.Duration) *ConsumptionHandler {
+	return &ConsumptionHandler{service: service, timeout: timeout}
 }
 
 // GetConsumption maneja la solicitud para obtener el consumo por periodo.
 // @Summary Obtiene el consumo de energía por periodo.
-// @Description Retorna el consumo de energía de los medidores en el rango de fechas especificado.
+// @Description Retorna el consumo de energía de los medidores en el rango de fechas especificado. period trae las etiquetas de cada periodo y period_start su inicio en RFC3339, que lo identifica aunque la etiqueta se repita.
 // @Tags consumption
 // @Accept json
 // @Produce json
 // @Param meter_ids query string true "IDs de los medidores separados por comas"
-// @Param start_date query string true "Fecha de inicio en formato YYYY-MM-DD"
-// @Param end_date query string true "Fecha de fin en formato YYYY-MM-DD"
-// @Param kind_period query string true "Tipo de periodo: daily, weekly, monthly"
+// @Param start_date query string false "Inicio del rango: fecha YYYY-MM-DD o instante RFC3339 (requerido sin range)"
+// @Param end_date query string false "Fin del rango: fecha YYYY-MM-DD, que incluye el día completo, o instante RFC3339 exclusivo (requerido sin range)"
+// @Param range query string false "Rango relativo que reemplaza a start_date y end_date: last_<n>h, last_<n>d, last_<n>w, last_<n>m, today, yesterday, this_week, previous_week, this_month, previous_month, this_year, previous_year"
+// @Param kind_period query string true "Tipo de periodo: quarter_hourly, hourly, daily, weekly, monthly, quarterly, yearly, interval"
+// @Param reducer query string false "Reducción por periodo: sum, mean, min, max, count (por defecto sum)"
+// @Param fill query string false "Relleno de periodos sin lecturas: null, zero (por defecto null)"
+// @Param week_start query string false "Inicio de semana para kind_period=weekly y los rangos this_week y previous_week; se valida con cualquier tipo de periodo: monday (ISO-8601, por defecto) o sunday"
+// @Param step query string false "Duración de cada periodo para kind_period=interval, p. ej. 6h"
+// @Param fiscal_year_start query int false "Mes de inicio del año fiscal (1-12) para quarterly y yearly"
+// @Param metrics query string false "Series a devolver separadas por comas: active, reactive_inductive, reactive_capacitive, exported, power_factor, apparent, reactive_penalty, inductive_penalty, capacitive_penalty, net, export_ratio, export_coverage (por defecto las siete primeras)"
+// @Param strict query bool false "Si es true, falla la consulta cuando algún medidor no se pudo obtener"
+// @Param tz query string false "Zona horaria IANA para los límites de los periodos, p. ej. America/Bogota (por defecto UTC)"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Failure 504 {object} map[string]string
 // @Router /consumption [get]
 func (h *ConsumptionHandler) GetConsumption(c echo.Context) error {
-	ctx := context.Background()
+	ctx := c.Request().Context()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
 
 	meterIDsStr := c.QueryParam("meters_ids")
 	startDate := c.QueryParam("start_date")
 	endDate := c.QueryParam("end_date")
+	timeRange := c.QueryParam("range")
 	kindPeriod := c.QueryParam("kind_period")
+	reducer := c.QueryParam("reducer")
+	fill := c.QueryParam("fill")
+	timezone := c.QueryParam("tz")
+	weekStart := c.QueryParam("week_start")
+	step := c.QueryParam("step")
+	fiscalYearStart := c.QueryParam("fiscal_year_start")
 
-	if meterIDsStr == "" || startDate == "" || endDate == "" || kindPeriod == "" {
+	if meterIDsStr == "" || kindPeriod == "" || (timeRange == "" && (startDate == "" || endDate == "")) {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Todos los parámetros son requeridos"})
 	}
-	start, err := time.Parse("2006-01-02", startDate)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido de start_date, debe ser YYYY-MM-DD"})
-	}
-	end, err := time.Parse("2006-01-02", endDate)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido de end_date, debe ser YYYY-MM-DD"})
-	}
-
-	if start.After(end) {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "start_date no puede ser mayor que end_date"})
-	}
 
 	var meterIDs []int
 	meterIDsList := strings.Split(meterIDsStr, ",")
@@ -69,10 +84,72 @@ func (h *ConsumptionHandler) GetConsumption(c echo.Context) error {
 		meterIDs = append(meterIDs, id)
 	}
 
-	results, err := h.service.GetConsumptionByPeriod(ctx, meterIDs, startDate, endDate, kindPeriod)
+	strict := false
+	if strictStr := c.QueryParam("strict"); strictStr != "" {
+		var err error
+		strict, err = strconv.ParseBool(strictStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido de strict, debe ser true o false"})
+		}
+	}
+
+	var metrics []string
+	if metricsStr := c.QueryParam("metrics"); metricsStr != "" {
+		for _, metric := range strings.Split(metricsStr, ",") {
+			metrics = append(metrics, strings.TrimSpace(metric))
+		}
+	}
+
+	results, err := h.service.GetConsumptionByPeriod(ctx, services.ConsumptionQuery{
+		MeterIDs:        meterIDs,
+		StartDate:       startDate,
+		EndDate:         endDate,
+		Range:           timeRange,
+		KindPeriod:      kindPeriod,
+		Reducer:         reducer,
+		Fill:            fill,
+		Timezone:        timezone,
+		WeekStart:       weekStart,
+		Step:            step,
+		FiscalYearStart: fiscalYearStart,
+		Metrics:         metrics,
+		Strict:          strict,
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "La consulta superó el tiempo máximo de respuesta"})
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	var meterNotFound *services.MeterNotFoundError
+	if errors.As(err, &meterNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{"error": err.Error(), "errors": meterNotFound.Errors()})
+	}
+	var partialFailure *services.PartialFailureError
+	if errors.As(err, &partialFailure) {
+		status := http.StatusBadGateway
+		if partialFailure.HasCode(services.ErrCodeConsumptionUnavailable) {
+			status = http.StatusInternalServerError
+		}
+		return c.JSON(status, map[string]interface{}{"error": err.Error(), "errors": partialFailure.Errors})
+	}
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	return c.JSON(http.StatusOK, results)
 }
+
+// GetPeriodKinds lista los tipos de periodo disponibles.
+// @Summary Lista los tipos de periodo.
+// @Description Retorna los valores aceptados en kind_period con su descripción y parámetros, y en parameters los que aplican a todos los tipos.
+// @Tags consumption
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /consumption/periods [get]
+func (h *ConsumptionHandler) GetPeriodKinds(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"periods":    h.service.ListPeriodKinds(),
+		"parameters": h.service.ListPeriodParameters(),
+	})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+	"github.com/SaidHernandez/bia-comsumtion/services"
+	"github.com/labstack/echo/v4"
+)
+
+// MeterHandler maneja las solicitudes de administración de medidores.
+type MeterHandler struct {
+	service *services.MeterService
+}
+
+// NewMeterHandler crea una nueva instancia de MeterHandler.
+func NewMeterHandler(service *services.MeterService) *MeterHandler {
+	return &MeterHandler{service: service}
+}
+
+// ListMeters lista los medidores registrados.
+// @Summary Lista los medidores.
+// @Tags meters
+// @Produce json
+// @Success 200 {array} model.Meter
+// @Failure 500 {object} map[string]string
+// @Router /meters [get]
+func (h *MeterHandler) ListMeters(c echo.Context) error {
+	meters, err := h.service.ListMeters(c.Request().Context())
+	if err != nil {
+		return meterError(c, err)
+	}
+	return c.JSON(http.StatusOK, meters)
+}
+
+// GetMeter obtiene un medidor por su ID.
+// @Summary Obtiene un medidor.
+// @Tags meters
+// @Produce json
+// @Param id path int true "ID del medidor"
+// @Success 200 {object} model.Meter
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /meters/{id} [get]
+func (h *MeterHandler) GetMeter(c echo.Context) error {
+	meterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido del ID del medidor"})
+	}
+
+	meter, err := h.service.GetMeter(c.Request().Context(), meterID)
+	if err != nil {
+		return meterError(c, err)
+	}
+	return c.JSON(http.StatusOK, meter)
+}
+
+// CreateMeter registra un medidor.
+// @Summary Registra un medidor.
+// @Description El id es obligatorio y debe coincidir con el meter_id de las lecturas. Los campos omitidos toman sus valores por defecto: type consumer, multiplier 1, timezone UTC y status active.
+// @Tags meters
+// @Accept json
+// @Produce json
+// @Param meter body model.Meter true "Medidor"
+// @Success 201 {object} model.Meter
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /meters [post]
+func (h *MeterHandler) CreateMeter(c echo.Context) error {
+	var meter model.Meter
+	if err := c.Bind(&meter); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cuerpo inválido del medidor"})
+	}
+
+	created, err := h.service.CreateMeter(c.Request().Context(), meter)
+	if err != nil {
+		return meterError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+// UpdateMeter reemplaza los datos de un medidor.
+// @Summary Actualiza un medidor.
+// @Tags meters
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del medidor"
+// @Param meter body model.Meter true "Medidor"
+// @Success 200 {object} model.Meter
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /meters/{id} [put]
+func (h *MeterHandler) UpdateMeter(c echo.Context) error {
+	meterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido del ID del medidor"})
+	}
+
+	var meter model.Meter
+	if err := c.Bind(&meter); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cuerpo inválido del medidor"})
+	}
+
+	updated, err := h.service.UpdateMeter(c.Request().Context(), meterID, meter)
+	if err != nil {
+		return meterError(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteMeter elimina un medidor.
+// @Summary Elimina un medidor.
+// @Tags meters
+// @Param id path int true "ID del medidor"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /meters/{id} [delete]
+func (h *MeterHandler) DeleteMeter(c echo.Context) error {
+	meterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido del ID del medidor"})
+	}
+
+	if err := h.service.DeleteMeter(c.Request().Context(), meterID); err != nil {
+		return meterError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// meterError traduce los errores del servicio de medidores a respuestas HTTP.
+func meterError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrMeterNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, repository.ErrMeterExists):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMeter):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/importer"
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+	"github.com/SaidHernandez/bia-comsumtion/services"
+	"github.com/labstack/echo/v4"
+)
+
+// MaxReadingsBodyBytes limita el tamaño del cuerpo al guardar lecturas; alcanza
+// para services.MaxSaveReadings lecturas con holgura.
+const MaxReadingsBodyBytes = 8 << 20
+
+// ReadingHandler maneja las solicitudes de lecturas crudas de los medidores.
+type ReadingHandler struct {
+	service *services.ReadingService
+	timeout time.Duration
+}
+
+// NewReadingHandler crea una nueva instancia de ReadingHandler. Si timeout es
+// mayor que cero, cada solicitud se cancela al superar ese tiempo.
+func NewReadingHandler(service *services.ReadingService, timeout time.Duration) *ReadingHandler {
+	return &ReadingHandler{service: service, timeout: timeout}
+}
+
+// GetReadings maneja la solicitud para obtener las lecturas crudas de un medidor.
+// @Summary Obtiene las lecturas crudas de un medidor.
+// @Description Retorna las lecturas del medidor ordenadas por fecha e id, paginadas por cursor.
+// @Tags readings
+// @Produce json
+// @Param id path int true "ID del medidor"
+// @Param start_date query string false "Inicio del rango: fecha YYYY-MM-DD o instante RFC3339"
+// @Param end_date query string false "Fin del rango: fecha YYYY-MM-DD, que incluye el día completo, o instante RFC3339 exclusivo"
+// @Param range query string false "Rango relativo que reemplaza a start_date y end_date, p. ej. last_7d o previous_month"
+// @Param cursor query string false "Valor de next_cursor de la página anterior"
+// @Param limit query int false "Máximo de lecturas por página (por defecto 100, máximo 1000)"
+// @Success 200 {object} services.ReadingPage
+// @Failure 400 {object} map[string]string
+// @Failure 504 {object} map[string]string
+// @Router /meters/{id}/readings [get]
+func (h *ReadingHandler) GetReadings(c echo.Context) error {
+	ctx := c.Request().Context()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	meterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido del ID del medidor"})
+	}
+
+	limit := 0
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido de limit"})
+		}
+	}
+
+	page, err := h.service.GetReadings(ctx, services.ReadingQuery{
+		MeterID:   meterID,
+		StartDate: c.QueryParam("start_date"),
+		EndDate:   c.QueryParam("end_date"),
+		Range:     c.QueryParam("range"),
+		Cursor:    c.QueryParam("cursor"),
+		Limit:     limit,
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "La consulta superó el tiempo máximo de respuesta"})
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// SaveMeterReadings maneja la solicitud para guardar lecturas de un medidor.
+// @Summary Guarda lecturas de un medidor.
+// @Description Recibe un arreglo JSON o un CSV con cabecera. meter_id es opcional y, si se envía, debe coincidir con el de la ruta. Las lecturas con un ID ya guardado se reemplazan y las de un medidor y fecha ya guardados con otro ID se resuelven según IMPORT_DUPLICATE_POLICY; si alguna es inválida no se guarda ninguna.
+// @Tags readings
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param id path int true "ID del medidor"
+// @Param readings body []model.Consumption true "Lecturas"
+// @Success 201 {object} map[string]int
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 504 {object} map[string]string
+// @Router /meters/{id}/readings [post]
+func (h *ReadingHandler) SaveMeterReadings(c echo.Context) error {
+	meterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || meterID < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Formato inválido del ID del medidor"})
+	}
+	return h.saveReadings(c, meterID)
+}
+
+// SaveReadings maneja la solicitud para guardar lecturas de varios medidores.
+// @Summary Guarda lecturas de varios medidores.
+// @Description Recibe un arreglo JSON o un CSV con cabecera, con hasta 10000 lecturas. Las lecturas con un ID ya guardado se reemplazan y las de un medidor y fecha ya guardados con otro ID se resuelven según IMPORT_DUPLICATE_POLICY; si alguna es inválida no se guarda ninguna.
+// @Tags readings
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param readings body []model.Consumption true "Lecturas"
+// @Success 201 {object} map[string]int
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 504 {object} map[string]string
+// @Router /readings [post]
+func (h *ReadingHandler) SaveReadings(c echo.Context) error {
+	return h.saveReadings(c, 0)
+}
+
+// saveReadings decodifica el cuerpo según su Content-Type y guarda las
+// lecturas. Si meterID no es cero todas deben ser de ese medidor.
+func (h *ReadingHandler) saveReadings(c echo.Context, meterID int) error {
+	ctx := c.Request().Context()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, MaxReadingsBodyBytes)
+	var readings []model.Consumption
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		if err := json.NewDecoder(body).Decode(&readings); err != nil {
+			if tooLarge(err) {
+				return bodyTooLarge(c)
+			}
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cuerpo inválido de las lecturas"})
+		}
+		if meterID != 0 {
+			var readingErrors []services.ReadingError
+			for i := range readings {
+				if readings[i].MeterID == 0 {
+					readings[i].MeterID = meterID
+				} else if readings[i].MeterID != meterID {
+					reason := fmt.Sprintf("meter_id %d does not match meter %d", readings[i].MeterID, meterID)
+					readingErrors = append(readingErrors, services.ReadingError{Index: i, ID: readings[i].ID, Reason: reason})
+				}
+			}
+			if len(readingErrors) > 0 {
+				return saveReadingsError(c, &services.InvalidReadingsError{Errors: readingErrors})
+			}
+		}
+	case "text/csv", "application/csv":
+		var rejects []importer.Reject
+		var err error
+		readings, rejects, err = importer.DecodeCSV(body, meterID)
+		if tooLarge(err) {
+			return bodyTooLarge(c)
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if len(rejects) > 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error":  fmt.Sprintf("%s: %d rejected", services.ErrInvalidReadings, len(rejects)),
+				"errors": rejects,
+			})
+		}
+	default:
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type no soportado, use application/json o text/csv"})
+	}
+
+	result, err := h.service.SaveReadings(ctx, readings)
+	if err != nil {
+		return saveReadingsError(c, err)
+	}
+	return c.JSON(http.StatusCreated, map[string]int{
+		"saved":   result.Inserted + result.Updated,
+		"skipped": result.Skipped,
+	})
+}
+
+// tooLarge indica si la lectura del cuerpo se cortó por superar MaxReadingsBodyBytes.
+func tooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func bodyTooLarge(c echo.Context) error {
+	return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+		"error": fmt.Sprintf("El cuerpo supera el máximo de %d MiB", MaxReadingsBodyBytes>>20),
+	})
+}
+
+// saveReadingsError traduce los errores al guardar lecturas a respuestas HTTP.
+func saveReadingsError(c echo.Context, err error) error {
+	var invalidReadings *services.InvalidReadingsError
+	var meterNotFound *services.MeterNotFoundError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "La operación superó el tiempo máximo de respuesta"})
+	case errors.Is(err, context.Canceled):
+		return err
+	case errors.As(err, &invalidReadings):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "errors": invalidReadings.Errors})
+	case errors.Is(err, services.ErrInvalidReadings):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.As(err, &meterNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{"error": err.Error(), "errors": meterNotFound.Errors()})
+	case errors.Is(err, repository.ErrDuplicateReading):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Otra solicitud guardó al mismo tiempo una lectura del mismo medidor y fecha, intente de nuevo"})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
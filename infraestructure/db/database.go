@@ -2,20 +2,71 @@ package db
 
 import (
 	"fmt"
+	"os"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
+// Drivers de base de datos soportados. postgres sirve también para TimescaleDB.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+const defaultSQLiteDSN = "bia_consumption.db"
+
+// memoryDSN abre una base SQLite en memoria, útil en pruebas.
+const memoryDSN = ":memory:"
 
-func InitDB() error {
-	var err error
-	dsn := "bia_consumption.db"
+// Config indica el driver y la cadena de conexión de la base de datos.
+type Config struct {
+	Driver string
+	DSN    string
+}
 
-	DB, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+// ConfigFromEnv lee la configuración de DB_DRIVER (sqlite por defecto) y DB_DSN.
+func ConfigFromEnv() Config {
+	return Config{
+		Driver: os.Getenv("DB_DRIVER"),
+		DSN:    os.Getenv("DB_DSN"),
+	}
+}
+
+// Open abre la conexión con el driver configurado.
+func Open(config Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch config.Driver {
+	case "", DriverSQLite:
+		dsn := config.DSN
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+		dialector = sqlite.Open(dsn)
+	case DriverPostgres:
+		if config.DSN == "" {
+			return nil, fmt.Errorf("DB_DSN is required for driver %s", config.Driver)
+		}
+		dialector = postgres.Open(config.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", config.Driver)
+	}
+
+	// TranslateError convierte los errores del driver en errores de gorm, como
+	// gorm.ErrDuplicatedKey, para tratarlos igual en todos los drivers.
+	database, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if config.DSN == memoryDSN {
+		// Cada conexión a :memory: abre una base distinta.
+		sqlDB, err := database.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
 	}
-	return nil
+	return database, nil
 }
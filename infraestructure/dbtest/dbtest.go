@@ -0,0 +1,28 @@
+// Package dbtest ofrece bases de datos para las pruebas de los paquetes que usan GORM.
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/db"
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/migrations"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Open abre una base SQLite en memoria con el esquema migrado. La conexión se
+// cierra al terminar la prueba.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	conn, err := db.Open(db.Config{Driver: db.DriverSQLite, DSN: ":memory:"})
+	require.NoError(t, err)
+	sqlDB, err := conn.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	_, err = migrations.NewMigrator(conn, migrations.All()).Up(context.Background())
+	require.NoError(t, err)
+	return conn
+}
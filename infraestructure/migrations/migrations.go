@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// All devuelve las migraciones del esquema en orden de versión. Cada migración
+// usa su propia copia de los modelos para no cambiar si el modelo cambia.
+func All() []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "create consumptions",
+			// Sin Down: la tabla puede existir desde antes de las migraciones y
+			// revertir borraría las lecturas.
+			Up: func(tx *gorm.DB) error {
+				// Las bases creadas antes con AutoMigrate ya tienen la tabla.
+				if tx.Migrator().HasTable(&consumptionV1{}) {
+					return nil
+				}
+				return tx.Migrator().CreateTable(&consumptionV1{})
+			},
+		},
+		{
+			Version:     2,
+			Description: "create meters",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.Migrator().CreateTable(&meterV2{}); err != nil {
+					return err
+				}
+				// Registra como medidores de consumo los que ya tienen lecturas.
+				err := tx.Exec(`INSERT INTO meters (id, serial, type, cumulative, multiplier, timezone, status, created_at, updated_at)
+					SELECT DISTINCT meter_id, CAST(meter_id AS TEXT), 'consumer', false, 1, 'UTC', 'active', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+					FROM consumptions`).Error
+				if err != nil {
+					return err
+				}
+				return seedCumulativeMeters(tx)
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&meterV2{})
+			},
+		},
+		{
+			Version:     3,
+			Description: "unique reading per meter and date",
+			Up: func(tx *gorm.DB) error {
+				// Las bases cargadas antes de la política de duplicados pueden
+				// tener lecturas repetidas (test_bia11.csv trae una). Como no se
+				// sabe cuál llegó primero, se conserva la de menor id.
+				err := tx.Exec(`DELETE FROM consumptions WHERE EXISTS (
+					SELECT 1 FROM consumptions kept
+					WHERE kept.meter_id = consumptions.meter_id
+						AND kept.date = consumptions.date
+						AND kept.id < consumptions.id)`).Error
+				if err != nil {
+					return err
+				}
+				return tx.Exec("CREATE UNIQUE INDEX idx_consumptions_meter_date ON consumptions (meter_id, date)").Error
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Exec("DROP INDEX idx_consumptions_meter_date").Error
+			},
+		},
+	}
+}
+
+// CumulativeMetersEnv es la variable con la lista de medidores acumulativos que
+// se usaba antes de la tabla meters. La migración VersionMeters la lee para
+// marcarlos como acumulativos; después se administran con la API de medidores.
+const CumulativeMetersEnv = "CUMULATIVE_METER_IDS"
+
+// VersionMeters es la versión de la migración que crea la tabla meters.
+const VersionMeters = 2
+
+// seedCumulativeMeters marca como acumulativos los medidores de
+// CumulativeMetersEnv y registra los que todavía no tienen lecturas, para que
+// una importación posterior los encuentre ya configurados.
+func seedCumulativeMeters(tx *gorm.DB) error {
+	value := strings.TrimSpace(os.Getenv(CumulativeMetersEnv))
+	if value == "" {
+		return nil
+	}
+
+	var meterIDs []int
+	for _, idStr := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil || id < 1 {
+			return fmt.Errorf("invalid %s: %q", CumulativeMetersEnv, value)
+		}
+		meterIDs = append(meterIDs, id)
+	}
+
+	for _, id := range meterIDs {
+		meter := meterV2{}
+		err := tx.Where(meterV2{ID: id}).Attrs(meterV2{
+			Serial:     strconv.Itoa(id),
+			Type:       "consumer",
+			Multiplier: 1,
+			Timezone:   "UTC",
+			Status:     "active",
+		}).FirstOrCreate(&meter).Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Model(&meterV2{}).Where("id IN ?", meterIDs).Update("cumulative", true).Error
+}
+
+type consumptionV1 struct {
+	ID                 string    `gorm:"primaryKey"`
+	MeterID            int       `gorm:"index"`
+	Date               time.Time `gorm:"index"`
+	ActiveEnergy       float64
+	ReactiveInductive  float64
+	ReactiveCapacitive float64
+	ExportedEnergy     float64
+}
+
+func (consumptionV1) TableName() string {
+	return "consumptions"
+}
+
+type meterV2 struct {
+	ID          int     `gorm:"primaryKey"`
+	Serial      string  `gorm:"uniqueIndex;not null"`
+	Type        string  `gorm:"not null"`
+	Cumulative  bool    `gorm:"not null"`
+	Multiplier  float64 `gorm:"not null"`
+	Timezone    string  `gorm:"not null"`
+	Tariff      string
+	InstalledAt *time.Time
+	RemovedAt   *time.Time
+	Status      string `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (meterV2) TableName() string {
+	return "meters"
+}
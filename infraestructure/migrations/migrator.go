@@ -0,0 +1,218 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaAhead indica que la base tiene migraciones aplicadas que este
+// binario no conoce, por lo que fue migrada por una versión más nueva.
+var ErrSchemaAhead = errors.New("database schema is ahead of this binary")
+
+// Migration es un cambio de esquema versionado. Up y Down se ejecutan dentro de
+// una transacción junto con el registro en schema_migrations.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *gorm.DB) error
+	// Down revierte Up; si es nil la migración no se puede revertir.
+	Down func(tx *gorm.DB) error
+}
+
+// MigrationStatus describe si una migración está aplicada en la base.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   *time.Time
+	// Unknown marca migraciones aplicadas que este binario no conoce.
+	Unknown bool
+}
+
+// schemaMigration es una fila de schema_migrations.
+type schemaMigration struct {
+	Version     int
+	Description string
+	AppliedAt   time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	description TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// Migrator aplica y revierte migraciones en orden de versión.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return &Migrator{db: db, migrations: sorted}
+}
+
+// Check falla con ErrSchemaAhead si hay migraciones aplicadas desconocidas.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := m.known()
+	for _, record := range applied {
+		if _, exists := known[record.Version]; !exists {
+			return fmt.Errorf("%w: unknown migration %d", ErrSchemaAhead, record.Version)
+		}
+	}
+	return nil
+}
+
+// Up aplica en orden las migraciones pendientes y devuelve las aplicadas.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.Check(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]bool, len(applied))
+	for _, record := range applied {
+		done[record.Version] = true
+	}
+
+	var migrated []Migration
+	for _, migration := range m.migrations {
+		if done[migration.Version] {
+			continue
+		}
+
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return migrated, fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		migrated = append(migrated, migration)
+	}
+	return migrated, nil
+}
+
+// Down revierte las últimas steps migraciones aplicadas y devuelve las revertidas.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := m.known()
+	var reverted []Migration
+	for i := len(applied) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration, exists := known[applied[i].Version]
+		if !exists {
+			return reverted, fmt.Errorf("%w: cannot roll back unknown migration %d", ErrSchemaAhead, applied[i].Version)
+		}
+		if migration.Down == nil {
+			return reverted, fmt.Errorf("migration %d (%s) cannot be rolled back", migration.Version, migration.Description)
+		}
+
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("failed to roll back migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// Status devuelve el estado de cada migración conocida y de las aplicadas
+// desconocidas, ordenadas por versión.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := make(map[int]time.Time, len(applied))
+	for _, record := range applied {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	known := m.known()
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if at, exists := appliedAt[migration.Version]; exists {
+			status.Applied = true
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		if _, exists := known[record.Version]; !exists {
+			at := record.AppliedAt
+			statuses = append(statuses, MigrationStatus{
+				Version:     record.Version,
+				Description: record.Description,
+				Applied:     true,
+				AppliedAt:   &at,
+				Unknown:     true,
+			})
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// applied crea schema_migrations si no existe y devuelve las migraciones
+// aplicadas ordenadas por versión.
+func (m *Migrator) applied(ctx context.Context) ([]schemaMigration, error) {
+	if err := m.db.WithContext(ctx).Exec(createSchemaMigrations).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var applied []schemaMigration
+	if err := m.db.WithContext(ctx).Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func (m *Migrator) known() map[int]Migration {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+	return known
+}
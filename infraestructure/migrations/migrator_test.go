@@ -0,0 +1,243 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	conn, err := db.Open(db.Config{Driver: db.DriverSQLite, DSN: ":memory:"})
+	require.NoError(t, err)
+	sqlDB, err := conn.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return conn
+}
+
+type widget struct {
+	ID   int
+	Name string
+}
+
+var testMigrations = []Migration{
+	{
+		Version:     2,
+		Description: "add widget name index",
+		Up:          func(tx *gorm.DB) error { return tx.Exec("CREATE INDEX idx_widgets_name ON widgets (name)").Error },
+		Down:        func(tx *gorm.DB) error { return tx.Migrator().DropIndex(&widget{}, "idx_widgets_name") },
+	},
+	{
+		Version:     1,
+		Description: "create widgets",
+		Up:          func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&widget{}) },
+		Down:        func(tx *gorm.DB) error { return tx.Migrator().DropTable(&widget{}) },
+	},
+}
+
+func versions(migrations []Migration) []int {
+	result := make([]int, len(migrations))
+	for i, migration := range migrations {
+		result[i] = migration.Version
+	}
+	return result
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	conn := openTestDB(t)
+	migrator := NewMigrator(conn, testMigrations)
+	ctx := context.Background()
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, versions(applied))
+	assert.True(t, conn.Migrator().HasIndex(&widget{}, "idx_widgets_name"))
+
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, versions(reverted))
+	assert.False(t, conn.Migrator().HasIndex(&widget{}, "idx_widgets_name"))
+	assert.True(t, conn.Migrator().HasTable(&widget{}))
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Equal(t, MigrationStatus{Version: 2, Description: "add widget name index"}, statuses[1])
+
+	reverted, err = migrator.Down(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, versions(reverted))
+	assert.False(t, conn.Migrator().HasTable(&widget{}))
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	conn := openTestDB(t)
+	migrator := NewMigrator(conn, []Migration{
+		testMigrations[1],
+		{
+			Version:     2,
+			Description: "broken",
+			Up:          func(tx *gorm.DB) error { return errors.New("boom") },
+		},
+	})
+
+	applied, err := migrator.Up(context.Background())
+	assert.EqualError(t, err, "failed to apply migration 2 (broken): boom")
+	assert.Equal(t, []int{1}, versions(applied))
+
+	statuses, err := migrator.Status(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestMigrator_SchemaAhead(t *testing.T) {
+	conn := openTestDB(t)
+	_, err := NewMigrator(conn, testMigrations).Up(context.Background())
+	require.NoError(t, err)
+
+	older := NewMigrator(conn, testMigrations[1:])
+
+	assert.ErrorIs(t, older.Check(context.Background()), ErrSchemaAhead)
+	_, err = older.Up(context.Background())
+	assert.ErrorIs(t, err, ErrSchemaAhead)
+	_, err = older.Down(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrSchemaAhead)
+
+	statuses, err := older.Status(context.Background())
+	assert.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[1].Unknown)
+}
+
+func TestMigrator_IrreversibleMigration(t *testing.T) {
+	conn := openTestDB(t)
+	migrator := NewMigrator(conn, []Migration{
+		{Version: 1, Description: "create widgets", Up: testMigrations[1].Up},
+	})
+
+	_, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	_, err = migrator.Down(context.Background(), 1)
+	assert.EqualError(t, err, "migration 1 (create widgets) cannot be rolled back")
+}
+
+func TestAll_AdoptsExistingConsumptionsTable(t *testing.T) {
+	conn := openTestDB(t)
+	require.NoError(t, conn.Migrator().CreateTable(&consumptionV1{}))
+
+	applied, err := NewMigrator(conn, All()).Up(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied[0].Version)
+	assert.True(t, conn.Migrator().HasIndex(&consumptionV1{}, "MeterID"))
+
+	migrator := NewMigrator(conn, All())
+	_, err = migrator.Down(context.Background(), len(applied))
+	assert.EqualError(t, err, "migration 1 (create consumptions) cannot be rolled back")
+	assert.True(t, conn.Migrator().HasTable(&consumptionV1{}))
+}
+
+func TestAll_RegistersMetersWithReadings(t *testing.T) {
+	conn := openTestDB(t)
+	require.NoError(t, conn.Migrator().CreateTable(&consumptionV1{}))
+	require.NoError(t, conn.Create([]consumptionV1{
+		{ID: "a", MeterID: 7, Date: time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)},
+		{ID: "b", MeterID: 7, Date: time.Date(2023, 7, 4, 10, 15, 0, 0, time.UTC)},
+		{ID: "c", MeterID: 3, Date: time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)},
+	}).Error)
+
+	_, err := NewMigrator(conn, All()).Up(context.Background())
+	require.NoError(t, err)
+
+	var meters []meterV2
+	require.NoError(t, conn.Order("id").Find(&meters).Error)
+	require.Len(t, meters, 2)
+	assert.Equal(t, 3, meters[0].ID)
+	assert.Equal(t, "3", meters[0].Serial)
+	assert.Equal(t, "consumer", meters[0].Type)
+	assert.Equal(t, 1.0, meters[0].Multiplier)
+	assert.Equal(t, 7, meters[1].ID)
+}
+
+func TestAll_SeedsCumulativeMeters(t *testing.T) {
+	t.Setenv(CumulativeMetersEnv, "7, 9")
+	conn := openTestDB(t)
+	require.NoError(t, conn.Migrator().CreateTable(&consumptionV1{}))
+	require.NoError(t, conn.Create([]consumptionV1{
+		{ID: "a", MeterID: 7, Date: time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)},
+		{ID: "b", MeterID: 3, Date: time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)},
+	}).Error)
+
+	_, err := NewMigrator(conn, All()).Up(context.Background())
+	require.NoError(t, err)
+
+	var meters []meterV2
+	require.NoError(t, conn.Order("id").Find(&meters).Error)
+	require.Len(t, meters, 3)
+	assert.Equal(t, 3, meters[0].ID)
+	assert.False(t, meters[0].Cumulative)
+	assert.Equal(t, 7, meters[1].ID)
+	assert.True(t, meters[1].Cumulative)
+	assert.Equal(t, 9, meters[2].ID)
+	assert.Equal(t, "9", meters[2].Serial)
+	assert.True(t, meters[2].Cumulative)
+}
+
+func TestAll_RejectsInvalidCumulativeMeters(t *testing.T) {
+	t.Setenv(CumulativeMetersEnv, "7,x")
+	conn := openTestDB(t)
+
+	applied, err := NewMigrator(conn, All()).Up(context.Background())
+
+	assert.Equal(t, []int{1}, versions(applied))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid CUMULATIVE_METER_IDS: "7,x"`)
+}
+
+func TestAll_UniqueReadingPerMeterAndDate(t *testing.T) {
+	conn := openTestDB(t)
+	_, err := NewMigrator(conn, All()).Up(context.Background())
+	require.NoError(t, err)
+
+	date := time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, conn.Create(&consumptionV1{ID: "a", MeterID: 1, Date: date}).Error)
+	require.NoError(t, conn.Create(&consumptionV1{ID: "b", MeterID: 2, Date: date}).Error)
+	assert.Error(t, conn.Create(&consumptionV1{ID: "c", MeterID: 1, Date: date}).Error)
+}
+
+func TestAll_RemovesExistingDuplicateReadings(t *testing.T) {
+	conn := openTestDB(t)
+	require.NoError(t, conn.Migrator().CreateTable(&consumptionV1{}))
+	date := time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, conn.Create([]consumptionV1{
+		{ID: "c", MeterID: 7, Date: date, ActiveEnergy: 3},
+		{ID: "a", MeterID: 7, Date: date, ActiveEnergy: 1},
+		{ID: "b", MeterID: 7, Date: date, ActiveEnergy: 2},
+		{ID: "d", MeterID: 8, Date: date, ActiveEnergy: 4},
+	}).Error)
+
+	applied, err := NewMigrator(conn, All()).Up(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, versions(applied))
+
+	var readings []consumptionV1
+	require.NoError(t, conn.Order("id").Find(&readings).Error)
+	require.Len(t, readings, 2)
+	assert.Equal(t, "a", readings[0].ID)
+	assert.Equal(t, 1.0, readings[0].ActiveEnergy)
+	assert.Equal(t, "d", readings[1].ID)
+	assert.True(t, conn.Migrator().HasIndex(&consumptionV1{}, "idx_consumptions_meter_date"))
+}
@@ -1,21 +1,22 @@
 package main
 
 import (
-	"encoding/csv"
-	"errors"
+	"context"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"strconv"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/SaidHernandez/bia-comsumtion/adapter"
-	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/aggregate"
+	"github.com/SaidHernandez/bia-comsumtion/business/importer"
 	"github.com/SaidHernandez/bia-comsumtion/business/repository"
 	handlers "github.com/SaidHernandez/bia-comsumtion/handler"
 	"github.com/SaidHernandez/bia-comsumtion/infraestructure/cache"
 	"github.com/SaidHernandez/bia-comsumtion/infraestructure/db"
+	"github.com/SaidHernandez/bia-comsumtion/infraestructure/migrations"
 	"github.com/SaidHernandez/bia-comsumtion/services"
 	"github.com/labstack/echo/v4"
 	echoSwagger "github.com/swaggo/echo-swagger"
@@ -23,123 +24,220 @@ import (
 )
 
 var consumptionHandler *handlers.ConsumptionHandler
+var readingHandler *handlers.ReadingHandler
+var meterHandler *handlers.MeterHandler
+
+// newConsumptionRepository crea el repositorio de consumos.
+// IMPORT_DUPLICATE_POLICY elige qué hacer con las lecturas repetidas
+// (keep_first, keep_last o reject), tanto al importar el archivo como al
+// guardar lecturas por la API.
+func newConsumptionRepository(database *gorm.DB) (*repository.ConsumptionRepository, error) {
+	consumptionRepository := repository.NewConsumptionRepository(database)
+	if name := os.Getenv("IMPORT_DUPLICATE_POLICY"); name != "" {
+		policy, err := repository.ParseDuplicatePolicy(name)
+		if err != nil {
+			return nil, err
+		}
+		consumptionRepository.SetDuplicatePolicy(policy)
+	}
+	return consumptionRepository, nil
+}
 
-func populateConsumptionDBFromCSV(db *gorm.DB, fileName string) error {
-	file, err := os.Open(fileName)
+// populateConsumptionDBFromCSV importa el archivo de lecturas y registra las
+// filas rechazadas en el log.
+func populateConsumptionDBFromCSV(database *gorm.DB, fileName string) error {
+	consumptionRepository, err := newConsumptionRepository(database)
 	if err != nil {
-		return fmt.Errorf("failed to open CSV file: %w", err)
+		return err
 	}
-	defer file.Close()
+	csvImporter := importer.NewImporter(consumptionRepository)
 
-	reader := csv.NewReader(file)
-	_, err = reader.Read()
+	file, err := os.Open(fileName)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
+		return fmt.Errorf("failed to open CSV file: %w", err)
 	}
+	defer file.Close()
 
-	var consumptions []model.Consumption
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			break
-		}
-		consumptionID := record[0]
-		if consumptionID == "" {
-			return fmt.Errorf("Consumption Id Invalido: %w", err)
-		}
-
-		meterID, err := strconv.Atoi(record[1])
-		if err != nil {
-			return fmt.Errorf("Invalido meterID formato: %w", err)
-		}
-		active, err := strconv.ParseFloat(record[2], 64)
-		if err != nil {
-			return fmt.Errorf("Invalido active energy formato: %w", err)
-		}
-		date, err := time.Parse("2006-01-02 15:04:05-07", record[3])
-		if err != nil {
-			return fmt.Errorf("Invalido date format: %w", err)
+	summary, err := csvImporter.ImportCSV(context.Background(), file)
+	if summary != nil {
+		log.Printf("import %s: %d inserted, %d updated, %d skipped, %d rejected",
+			fileName, summary.Inserted, summary.Updated, summary.Skipped, summary.Rejected)
+		if summary.Rejected > 0 {
+			if err := importer.WriteRejects(log.Writer(), summary.Rejects); err != nil {
+				return err
+			}
 		}
+	}
+	return err
+}
 
-		reactiveInductive := rand.Float64() * 20000
-		reactiveCapacitive := rand.Float64() * 20000
-		exported := rand.Float64() * 20000
-
-		consumptions = append(consumptions, model.Consumption{
-			ID:                 consumptionID,
-			MeterID:            meterID,
-			Date:               date,
-			ActiveEnergy:       active,
-			ReactiveInductive:  reactiveInductive,
-			ReactiveCapacitive: reactiveCapacitive,
-			ExportedEnergy:     exported,
-		})
+func initDB() (*gorm.DB, error) {
+	var command string
 
+	if len(os.Args) >= 2 {
+		command = os.Args[1]
 	}
 
-	if len(consumptions) == 0 {
-		return errors.New("no valid consumption records found")
+	database, err := db.Open(db.ConfigFromEnv())
+	if err != nil {
+		return nil, err
 	}
 
-	batchSize := 500
-	for i := 0; i < len(consumptions); i += batchSize {
-		end := i + batchSize
-		if end > len(consumptions) {
-			end = len(consumptions)
-		}
+	// Up no arranca si la base fue migrada por una versión más nueva.
+	applied, err := migrations.NewMigrator(database, migrations.All()).Up(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	warnCumulativeMeters(applied)
 
-		if err := db.Create(consumptions[i:end]).Error; err != nil {
-			return fmt.Errorf("failed to insert records into database: %w", err)
+	if command == "runMigration" {
+		err = populateConsumptionDBFromCSV(database, "./infraestructure/resources/test_bia11.csv")
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
 
-	return nil
+	return database, nil
 }
 
-func initDB() error {
-	var command string
-
-	if len(os.Args) >= 2 {
-		command = os.Args[1]
+// warnCumulativeMeters avisa que CUMULATIVE_METER_IDS ya no cambia nada si la
+// tabla meters se creó en un arranque anterior.
+func warnCumulativeMeters(applied []migrations.Migration) {
+	if os.Getenv(migrations.CumulativeMetersEnv) == "" {
+		return
 	}
+	for _, migration := range applied {
+		if migration.Version == migrations.VersionMeters {
+			return
+		}
+	}
+	log.Printf("%s is only read when the meters table is created; change cumulative meters with PUT /meters/:id",
+		migrations.CumulativeMetersEnv)
+}
 
-	var err error
-	err = db.InitDB()
+// runMigrateCommand atiende "migrate up", "migrate down [n]" y "migrate status".
+func runMigrateCommand(args []string) error {
+	database, err := db.Open(db.ConfigFromEnv())
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return err
 	}
+	migrator := migrations.NewMigrator(database, migrations.All())
+	ctx := context.Background()
 
-	if err := db.DB.AutoMigrate(&model.Consumption{}); err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
 	}
 
-	if command == "runMigration" {
-		err = populateConsumptionDBFromCSV(db.DB, "./infraestructure/resources/test_bia11.csv")
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Description)
+		}
+		if err == nil {
+			warnCumulativeMeters(applied)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations to roll back: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("rolled back %d %s\n", migration.Version, migration.Description)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Unknown:
+				state = "unknown, applied " + status.AppliedAt.Format(time.RFC3339)
+			case status.Applied:
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d %s: %s\n", status.Version, status.Description, state)
 		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command: %s (use up, down [n] or status)", action)
 	}
+}
+
+const defaultRequestTimeout = 30 * time.Second
 
-	return nil
+// requestTimeout lee el tiempo máximo por solicitud de REQUEST_TIMEOUT (p. ej. 10s).
+func requestTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("REQUEST_TIMEOUT"))
+	if err != nil {
+		return defaultRequestTimeout
+	}
+	return timeout
 }
 
-func initServices() {
+func initServices(database *gorm.DB) {
 
 	cacheInstance := cache.NewMemoryCache()
 	adapterInstance := adapter.NewAddressAdapter()
-	repository := repository.NewConsumptionRepository()
+	consumptionRepository, err := newConsumptionRepository(database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	meterRepository := repository.NewMeterRepository(database)
 
 	addressService := services.NewAddressServiceClient(cacheInstance, adapterInstance)
-	consumptionService := services.NewConsumptionService(addressService, repository)
-	consumptionHandler = handlers.NewConsumptionHandler(consumptionService)
+	consumptionService := services.NewConsumptionService(addressService, consumptionRepository, meterRepository, aggregate.NewDefaultRegistry())
+	if ratio, err := strconv.ParseFloat(os.Getenv("REACTIVE_PENALTY_RATIO"), 64); err == nil {
+		consumptionService.SetReactivePenaltyRatio(ratio)
+	}
+	if limit, err := strconv.Atoi(os.Getenv("CONSUMPTION_MAX_CONCURRENCY")); err == nil {
+		consumptionService.SetMaxConcurrency(limit)
+	}
+	if limit, err := strconv.Atoi(os.Getenv("CONSUMPTION_MAX_PERIODS")); err == nil {
+		consumptionService.SetMaxPeriods(limit)
+	}
+	consumptionHandler = handlers.NewConsumptionHandler(consumptionService, requestTimeout())
+
+	readingService := services.NewReadingService(consumptionRepository, meterRepository)
+	readingHandler = handlers.NewReadingHandler(readingService, requestTimeout())
+
+	meterHandler = handlers.NewMeterHandler(services.NewMeterService(meterRepository))
 }
 
 func main() {
-	initDB()
-	initServices()
+	if len(os.Args) >= 2 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	database, err := initDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	initServices(database)
 
 	e := echo.New()
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 	e.GET("/consumption", consumptionHandler.GetConsumption)
+	e.GET("/consumption/periods", consumptionHandler.GetPeriodKinds)
+	e.GET("/meters", meterHandler.ListMeters)
+	e.POST("/meters", meterHandler.CreateMeter)
+	e.GET("/meters/:id", meterHandler.GetMeter)
+	e.PUT("/meters/:id", meterHandler.UpdateMeter)
+	e.DELETE("/meters/:id", meterHandler.DeleteMeter)
+	e.GET("/meters/:id/readings", readingHandler.GetReadings)
+	e.POST("/meters/:id/readings", readingHandler.SaveMeterReadings)
+	e.POST("/readings", readingHandler.SaveReadings)
 	e.Logger.Fatal(e.Start(":8080"))
 }
@@ -44,7 +44,7 @@ func (client *AddressService) getAddressAdapter(ctx context.Context, meterId int
 	var address *adapter.Address
 	var err error
 
-	address, err = client.adapter.GetAddress(meterId)
+	address, err = client.adapter.GetAddress(ctx, meterId)
 	if err == nil {
 		cacheKey := fmt.Sprintf("address-%d", meterId)
 		err := client.cache.Set(ctx, cacheKey, address, 24*time.Hour)
@@ -34,8 +34,8 @@ type MockAdapter struct {
 	mock.Mock
 }
 
-func (m *MockAdapter) GetAddress(meterId int) (*adapter.Address, error) {
-	args := m.Called(meterId)
+func (m *MockAdapter) GetAddress(ctx context.Context, meterId int) (*adapter.Address, error) {
+	args := m.Called(ctx, meterId)
 	return args.Get(0).(*adapter.Address), args.Error(1)
 }
 
@@ -71,7 +71,7 @@ func TestAddressService_GetAddress(t *testing.T) {
 			},
 			mockAdapter: func() *MockAdapter {
 				adapterMock := new(MockAdapter)
-				adapterMock.On("GetAddress", 2).Return(&adapter.Address{ID: 2, Address: "Main St"}, nil)
+				adapterMock.On("GetAddress", mock.Anything, 2).Return(&adapter.Address{ID: 2, Address: "Main St"}, nil)
 				return adapterMock
 			},
 			expectedError: nil,
@@ -100,7 +100,7 @@ func TestAddressService_GetAddress(t *testing.T) {
 			mockAdapter: func() *MockAdapter {
 				adapterMock := new(MockAdapter)
 				// Devolver un puntero nulo explícito
-				adapterMock.On("GetAddress", 4).Return((*adapter.Address)(nil), errors.New("adapter error"))
+				adapterMock.On("GetAddress", mock.Anything, 4).Return((*adapter.Address)(nil), errors.New("adapter error"))
 				return adapterMock
 			},
 			expectedError: errors.New("no se pudo obtener la dirección después de varios intentos: adapter error"),
@@ -117,7 +117,7 @@ func TestAddressService_GetAddress(t *testing.T) {
 			},
 			mockAdapter: func() *MockAdapter {
 				adapterMock := new(MockAdapter)
-				adapterMock.On("GetAddress", 5).Return(&adapter.Address{ID: 2, Address: "Main St"}, nil)
+				adapterMock.On("GetAddress", mock.Anything, 5).Return(&adapter.Address{ID: 2, Address: "Main St"}, nil)
 				return adapterMock
 			},
 			expectedError: errors.New("error al almacenar la dirección en la caché: cache store error"),
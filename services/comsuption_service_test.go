@@ -2,10 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"math"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/SaidHernandez/bia-comsumtion/adapter"
+	"github.com/SaidHernandez/bia-comsumtion/business/aggregate"
 	"github.com/SaidHernandez/bia-comsumtion/business/model"
 	"github.com/SaidHernandez/bia-comsumtion/business/repository"
 	"github.com/stretchr/testify/assert"
@@ -27,20 +31,122 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) GetConsumptionByFilters(meterID int, startDate, endDate string) ([]model.Consumption, error) {
-	args := m.Called(meterID, startDate, endDate)
+func (m *MockRepository) GetConsumptionByFilters(ctx context.Context, meterID int, start, end time.Time, fields []string) ([]model.Consumption, error) {
+	args := m.Called(ctx, meterID, start, end, fields)
+	return args.Get(0).([]model.Consumption), args.Error(1)
+}
+
+func (m *MockRepository) GetConsumptionByMeters(ctx context.Context, meterIDs []int, start, end time.Time, fields []string) ([]model.Consumption, error) {
+	args := m.Called(ctx, meterIDs, start, end, fields)
+	return args.Get(0).([]model.Consumption), args.Error(1)
+}
+
+func (m *MockRepository) GetLastReadingsBefore(ctx context.Context, meterIDs []int, before time.Time, fields []string) ([]model.Consumption, error) {
+	args := m.Called(ctx, meterIDs, before, fields)
 	return args.Get(0).([]model.Consumption), args.Error(1)
 }
 
 var _ repository.ConsumptionRepositoryInterface = (*MockRepository)(nil)
 
+// MockAggregatingRepository además agrupa los consumos en la base de datos.
+type MockAggregatingRepository struct {
+	MockRepository
+}
+
+func (m *MockAggregatingRepository) GetAggregatedConsumption(ctx context.Context, meterIDs []int, start, end time.Time, unit, function string, fields []string) ([]model.Consumption, error) {
+	args := m.Called(ctx, meterIDs, start, end, unit, function, fields)
+	return args.Get(0).([]model.Consumption), args.Error(1)
+}
+
+var _ repository.AggregatingRepositoryInterface = (*MockAggregatingRepository)(nil)
+
+// registeredMeters devuelve un repositorio de medidores en el que existen los
+// IDs indicados, marcando como acumulados los de cumulative.
+func registeredMeters(meterIDs []int, cumulative []int) *MockMeterRepository {
+	isCumulative := make(map[int]bool, len(cumulative))
+	for _, meterID := range cumulative {
+		isCumulative[meterID] = true
+	}
+
+	meters := make([]model.Meter, 0, len(meterIDs))
+	for _, meterID := range meterIDs {
+		meters = append(meters, model.Meter{ID: meterID, Cumulative: isCumulative[meterID], Multiplier: 1})
+	}
+
+	meterMock := new(MockMeterRepository)
+	meterMock.On("GetMetersByIDs", mock.Anything, meterIDs).Return(meters, nil).Maybe()
+	return meterMock
+}
+
+func ptr(value float64) *float64 {
+	return &value
+}
+
+func flag(value bool) *bool {
+	return &value
+}
+
+// sparseFlags construye una serie de marcas de n periodos con valores solo en las posiciones indicadas.
+func sparseFlags(n int, values map[int]bool) []*bool {
+	series := make([]*bool, n)
+	for i, value := range values {
+		series[i] = flag(value)
+	}
+	return series
+}
+
+// sparse construye una serie de n periodos con valores solo en las posiciones indicadas.
+func sparse(n int, values map[int]float64) []*float64 {
+	series := make([]*float64, n)
+	for i, value := range values {
+		series[i] = ptr(value)
+	}
+	return series
+}
+
+// clockLabels construye las etiquetas de los periodos de un día divididos cada step.
+// day devuelve la medianoche de la fecha en loc.
+func day(date string, loc *time.Location) time.Time {
+	start, _ := time.ParseInLocation("2006-01-02", date, loc)
+	return start
+}
+
+func clockLabels(day time.Time, step time.Duration) []string {
+	var labels []string
+	for start := day; start.Before(day.AddDate(0, 0, 1)); start = start.Add(step) {
+		labels = append(labels, start.Format("Jan 2 15:04"))
+	}
+	return labels
+}
+
+func clockStarts(day time.Time, step time.Duration) []string {
+	var starts []string
+	for start := day; start.Before(day.AddDate(0, 0, 1)); start = start.Add(step) {
+		starts = append(starts, start.Format(time.RFC3339))
+	}
+	return starts
+}
+
 func TestConsumptionService_GetConsumptionByPeriod(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	bogota, _ := time.LoadLocation("America/Bogota")
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+
 	tests := []struct {
 		name            string
 		meterIDs        []int
 		startDate       string
 		endDate         string
 		kindPeriod      string
+		reducer         string
+		fill            string
+		timezone        string
+		weekStart       string
+		step            string
+		fiscalYearStart string
+		metrics         []string
+		strict          bool
+		cumulative      []int
 		mockAddress     func() AddressServiceInterface
 		mockRepository  func() repository.ConsumptionRepositoryInterface
 		expectedResults map[string]interface{}
@@ -49,8 +155,8 @@ func TestConsumptionService_GetConsumptionByPeriod(t *testing.T) {
 		{
 			name:       "Success: Get monthly consumption data",
 			meterIDs:   []int{1, 2},
-			startDate:  "2023-06-01",
-			endDate:    "2023-06-30",
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
 			kindPeriod: "monthly",
 			mockAddress: func() AddressServiceInterface {
 				addressMock := new(MockAddressService)
@@ -62,35 +168,40 @@ func TestConsumptionService_GetConsumptionByPeriod(t *testing.T) {
 				repoMock := new(MockRepository)
 				dateStr := "2023-07-04 10:59:00+00"
 				date, _ := time.Parse("2006-01-02 15:04:05-07", dateStr)
-				repoMock.On("GetConsumptionByFilters", 1, "2023-06-01", "2023-06-30").Return([]model.Consumption{
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1, 2}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{
 					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 50, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date},
-				}, nil)
-
-				repoMock.On("GetConsumptionByFilters", 2, "2023-06-01", "2023-06-30").Return([]model.Consumption{
 					{ID: "2", MeterID: 2, ActiveEnergy: 200, ReactiveInductive: 100, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date},
 				}, nil)
 
 				return repoMock
 			},
 			expectedResults: map[string]interface{}{
-				"period": []string{"Jul 2023"},
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 2023"},
+				"period_start": []string{"2023-07-01T00:00:00Z"},
 				"data_graph": []map[string]interface{}{
 					{
-						"active":              []float64{200},
-						"reactive_inductive":  []float64{100},
-						"reactive_capacitive": []float64{0},
-						"exported":            []float64{0},
-						"address":             "456 Side St",
-						"meter_id":            2,
-					},
-					{
-						"active":              []float64{100},
-						"reactive_inductive":  []float64{50},
-						"reactive_capacitive": []float64{0},
-						"exported":            []float64{0},
+						"active":              []*float64{ptr(100)},
+						"reactive_inductive":  []*float64{ptr(50)},
+						"reactive_capacitive": []*float64{ptr(0)},
+						"exported":            []*float64{ptr(0)},
+						"power_factor":        []*float64{ptr(0.8944)},
+						"apparent":            []*float64{ptr(math.Hypot(100, 50))},
+						"reactive_penalty":    []*bool{flag(false)},
 						"address":             "123 Main St",
 						"meter_id":            1,
 					},
+					{
+						"active":              []*float64{ptr(200)},
+						"reactive_inductive":  []*float64{ptr(100)},
+						"reactive_capacitive": []*float64{ptr(0)},
+						"exported":            []*float64{ptr(0)},
+						"power_factor":        []*float64{ptr(0.8944)},
+						"apparent":            []*float64{ptr(math.Hypot(200, 100))},
+						"reactive_penalty":    []*bool{flag(false)},
+						"address":             "456 Side St",
+						"meter_id":            2,
+					},
 				},
 			},
 			expectedError: nil,
@@ -101,6 +212,7 @@ func TestConsumptionService_GetConsumptionByPeriod(t *testing.T) {
 			startDate:  "2023-06-01",
 			endDate:    "2023-06-30",
 			kindPeriod: "weekly",
+			weekStart:  "sunday",
 			mockAddress: func() AddressServiceInterface {
 				addressMock := new(MockAddressService)
 				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
@@ -114,12 +226,9 @@ func TestConsumptionService_GetConsumptionByPeriod(t *testing.T) {
 				date2Str := "2023-06-10 10:59:00+00"
 				date2, _ := time.Parse("2006-01-02 15:04:05-07", date2Str)
 
-				repoMock.On("GetConsumptionByFilters", 1, "2023-06-01", "2023-06-30").Return([]model.Consumption{
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1, 2}, day("2023-06-01", time.UTC), day("2023-07-01", time.UTC), mock.Anything).Return([]model.Consumption{
 					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 50, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
 					{ID: "1", MeterID: 1, ActiveEnergy: 150, ReactiveInductive: 70, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
-				}, nil)
-
-				repoMock.On("GetConsumptionByFilters", 2, "2023-06-01", "2023-06-30").Return([]model.Consumption{
 					{ID: "2", MeterID: 2, ActiveEnergy: 200, ReactiveInductive: 100, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
 					{ID: "2", MeterID: 2, ActiveEnergy: 250, ReactiveInductive: 120, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
 				}, nil)
@@ -127,24 +236,118 @@ func TestConsumptionService_GetConsumptionByPeriod(t *testing.T) {
 				return repoMock
 			},
 			expectedResults: map[string]interface{}{
+				"errors": []MeterError{},
 				"period": []string{
-					"May 28 - Jun 3",
-					"Jun 4 - Jun 10",
+					"May 28 2023 - Jun 3 2023",
+					"Jun 4 2023 - Jun 10 2023",
+					"Jun 11 2023 - Jun 17 2023",
+					"Jun 18 2023 - Jun 24 2023",
+					"Jun 25 2023 - Jul 1 2023",
 				},
+				"period_start": []string{"2023-05-28T00:00:00Z", "2023-06-04T00:00:00Z", "2023-06-11T00:00:00Z", "2023-06-18T00:00:00Z", "2023-06-25T00:00:00Z"},
 				"data_graph": []map[string]interface{}{
 					{
-						"active":              []float64{200, 250},
-						"reactive_inductive":  []float64{100, 120},
-						"reactive_capacitive": []float64{0, 0},
-						"exported":            []float64{0, 0},
+						"active":              []*float64{ptr(100), ptr(150), nil, nil, nil},
+						"reactive_inductive":  []*float64{ptr(50), ptr(70), nil, nil, nil},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0), nil, nil, nil},
+						"exported":            []*float64{ptr(0), ptr(0), nil, nil, nil},
+						"power_factor":        []*float64{ptr(0.8944), ptr(0.9062), nil, nil, nil},
+						"apparent":            []*float64{ptr(math.Hypot(100, 50)), ptr(math.Hypot(150, 70)), nil, nil, nil},
+						"reactive_penalty":    []*bool{flag(false), flag(false), nil, nil, nil},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+					{
+						"active":              []*float64{ptr(200), ptr(250), nil, nil, nil},
+						"reactive_inductive":  []*float64{ptr(100), ptr(120), nil, nil, nil},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0), nil, nil, nil},
+						"exported":            []*float64{ptr(0), ptr(0), nil, nil, nil},
+						"power_factor":        []*float64{ptr(0.8944), ptr(0.9015), nil, nil, nil},
+						"apparent":            []*float64{ptr(math.Hypot(200, 100)), ptr(math.Hypot(250, 120)), nil, nil, nil},
+						"reactive_penalty":    []*bool{flag(false), flag(false), nil, nil, nil},
 						"address":             "456 Side St",
 						"meter_id":            2,
 					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Reduce monthly consumption with max",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			reducer:    "max",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:59:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-20 10:59:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 50, ReactiveCapacitive: 5, ExportedEnergy: 1, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 300, ReactiveInductive: 20, ReactiveCapacitive: 7, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 2023"},
+				"period_start": []string{"2023-07-01T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(300)},
+						"reactive_inductive":  []*float64{ptr(50)},
+						"reactive_capacitive": []*float64{ptr(7)},
+						"exported":            []*float64{ptr(1)},
+						"power_factor":        []*float64{ptr(0.9897)},
+						"apparent":            []*float64{ptr(math.Hypot(400, 58))},
+						"reactive_penalty":    []*bool{flag(true)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Sum readings in the same day by default",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-04",
+			kindPeriod: "daily",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:59:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 11:59:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-05", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 50, ReactiveCapacitive: 5, ExportedEnergy: 1, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 300, ReactiveInductive: 20, ReactiveCapacitive: 7, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 4"},
+				"period_start": []string{"2023-07-04T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
 					{
-						"active":              []float64{100, 150},
-						"reactive_inductive":  []float64{50, 70},
-						"reactive_capacitive": []float64{0, 0},
-						"exported":            []float64{0, 0},
+						"active":              []*float64{ptr(400)},
+						"reactive_inductive":  []*float64{ptr(70)},
+						"reactive_capacitive": []*float64{ptr(12)},
+						"exported":            []*float64{ptr(1)},
+						"power_factor":        []*float64{ptr(0.9897)},
+						"apparent":            []*float64{ptr(math.Hypot(400, 58))},
+						"reactive_penalty":    []*bool{flag(true)},
 						"address":             "123 Main St",
 						"meter_id":            1,
 					},
@@ -152,23 +355,1237 @@ func TestConsumptionService_GetConsumptionByPeriod(t *testing.T) {
 			},
 			expectedError: nil,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			addressService := tt.mockAddress()
-			repo := tt.mockRepository()
-			service := NewConsumptionService(addressService, repo)
-
-			results, err := service.GetConsumptionByPeriod(context.Background(), tt.meterIDs, tt.startDate, tt.endDate, tt.kindPeriod)
-
-			if tt.expectedError != nil {
-				assert.Error(t, err)
-				assert.Contains(t, err.Error(), tt.expectedError.Error())
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tt.expectedResults, results)
-			}
-		})
-	}
+		{
+			name:       "Success: Convert cumulative register readings into deltas",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-04",
+			kindPeriod: "daily",
+			cumulative: []int{1},
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 12:00:00+00")
+				date3, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 14:00:00+00")
+				date4, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 16:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-05", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "4", MeterID: 1, ActiveEnergy: 5, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date4},
+					{ID: "3", MeterID: 1, ActiveEnergy: 130, ReactiveInductive: 20, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date3},
+					{ID: "2", MeterID: 1, ActiveEnergy: 110, ReactiveInductive: 15, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 10, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+				}, nil)
+				// La lectura anterior al rango aporta el consumo hasta la primera del día.
+				repoMock.On("GetLastReadingsBefore", mock.Anything, []int{1}, day("2023-07-04", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "0", MeterID: 1, ActiveEnergy: 95, ReactiveInductive: 9, Date: day("2023-07-03", time.UTC).Add(23 * time.Hour)},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 4"},
+				"period_start": []string{"2023-07-04T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(40)},
+						"reactive_inductive":  []*float64{ptr(12)},
+						"reactive_capacitive": []*float64{ptr(0)},
+						"exported":            []*float64{ptr(0)},
+						"power_factor":        []*float64{ptr(0.9578)},
+						"apparent":            []*float64{ptr(math.Hypot(40, 12))},
+						"reactive_penalty":    []*bool{flag(false)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Fill missing periods with zero on a common axis",
+			meterIDs:   []int{2, 1},
+			startDate:  "2023-07-03",
+			endDate:    "2023-07-05",
+			kindPeriod: "daily",
+			fill:       "zero",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				addressMock.On("GetAddress", mock.Anything, 2).Return(&adapter.Address{Address: "456 Side St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-03 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-05 23:30:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{2, 1}, day("2023-07-03", time.UTC), day("2023-07-06", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 10, ReactiveCapacitive: 1, ExportedEnergy: 2, Date: date2},
+					{ID: "2", MeterID: 1, ActiveEnergy: 50, ReactiveInductive: 5, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "3", MeterID: 2, ActiveEnergy: 70, ReactiveInductive: 7, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 3", "Jul 4", "Jul 5"},
+				"period_start": []string{"2023-07-03T00:00:00Z", "2023-07-04T00:00:00Z", "2023-07-05T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(0), ptr(0), ptr(70)},
+						"reactive_inductive":  []*float64{ptr(0), ptr(0), ptr(7)},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0), ptr(0)},
+						"exported":            []*float64{ptr(0), ptr(0), ptr(0)},
+						"power_factor":        []*float64{nil, nil, ptr(0.995)},
+						"apparent":            []*float64{ptr(math.Hypot(0, 0)), ptr(math.Hypot(0, 0)), ptr(math.Hypot(70, 7))},
+						"reactive_penalty":    []*bool{flag(false), flag(false), flag(false)},
+						"address":             "456 Side St",
+						"meter_id":            2,
+					},
+					{
+						"active":              []*float64{ptr(50), ptr(0), ptr(100)},
+						"reactive_inductive":  []*float64{ptr(5), ptr(0), ptr(10)},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0), ptr(1)},
+						"exported":            []*float64{ptr(0), ptr(0), ptr(2)},
+						"power_factor":        []*float64{ptr(0.995), nil, ptr(0.996)},
+						"apparent":            []*float64{ptr(math.Hypot(50, 5)), ptr(math.Hypot(0, 0)), ptr(math.Hypot(100, 9))},
+						"reactive_penalty":    []*bool{flag(false), flag(false), flag(true)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Flag periods with reactive energy penalty",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-05",
+			kindPeriod: "daily",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-05 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-06", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 40, ReactiveCapacitive: 20, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 0, ReactiveInductive: 0, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 4", "Jul 5"},
+				"period_start": []string{"2023-07-04T00:00:00Z", "2023-07-05T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(100), ptr(0)},
+						"reactive_inductive":  []*float64{ptr(40), ptr(0)},
+						"reactive_capacitive": []*float64{ptr(20), ptr(0)},
+						"exported":            []*float64{ptr(0), ptr(0)},
+						"power_factor":        []*float64{ptr(0.9806), nil},
+						"apparent":            []*float64{ptr(math.Hypot(100, 20)), ptr(0)},
+						"reactive_penalty":    []*bool{flag(true), flag(false)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Flag inductive and capacitive penalties separately",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-06",
+			kindPeriod: "daily",
+			metrics:    []string{"reactive_penalty", "inductive_penalty", "capacitive_penalty"},
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-05 10:00:00+00")
+				date3, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-06 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-07", time.UTC), []string{"active_energy", "reactive_inductive", "reactive_capacitive"}).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 60, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 10, ReactiveCapacitive: 5, Date: date2},
+					{ID: "3", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 10, Date: date3},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 4", "Jul 5", "Jul 6"},
+				"period_start": []string{"2023-07-04T00:00:00Z", "2023-07-05T00:00:00Z", "2023-07-06T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"reactive_penalty":   []*bool{flag(true), flag(true), flag(false)},
+						"inductive_penalty":  []*bool{flag(true), flag(false), flag(false)},
+						"capacitive_penalty": []*bool{flag(false), flag(true), flag(false)},
+						"address":            "123 Main St",
+						"meter_id":           1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Compute prosumer balance metrics",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-06",
+			kindPeriod: "daily",
+			fill:       "zero",
+			metrics:    []string{"active", "exported", "net", "export_ratio", "export_coverage"},
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-05 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-07", time.UTC), []string{"active_energy", "exported_energy"}).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ExportedEnergy: 40, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 10, ExportedEnergy: 30, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 4", "Jul 5", "Jul 6"},
+				"period_start": []string{"2023-07-04T00:00:00Z", "2023-07-05T00:00:00Z", "2023-07-06T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":          []*float64{ptr(100), ptr(10), ptr(0)},
+						"exported":        []*float64{ptr(40), ptr(30), ptr(0)},
+						"net":             []*float64{ptr(60), ptr(-20), ptr(0)},
+						"export_ratio":    []*float64{ptr(0.2857), ptr(0.75), nil},
+						"export_coverage": []*float64{ptr(0.4), ptr(1), nil},
+						"address":         "123 Main St",
+						"meter_id":        1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Load only the columns needed for the power factor",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			metrics:    []string{"power_factor"},
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), []string{"active_energy", "reactive_inductive", "reactive_capacitive"}).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, ReactiveInductive: 40, ReactiveCapacitive: 20, Date: date},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 2023"},
+				"period_start": []string{"2023-07-01T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"power_factor": []*float64{ptr(0.9806)},
+						"address":      "123 Main St",
+						"meter_id":     1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Report meters that could not be fetched",
+			meterIDs:   []int{1, 3},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			metrics:    []string{"active"},
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				addressMock.On("GetAddress", mock.Anything, 3).Return((*adapter.Address)(nil), errors.New("address service down"))
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1, 3}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 100, Date: date},
+					{ID: "3", MeterID: 3, ActiveEnergy: 50, Date: date},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors": []MeterError{
+					{MeterID: 3, Code: ErrCodeAddressUnavailable, Reason: "address is temporarily unavailable"},
+				},
+				"period":       []string{"Jul 2023"},
+				"period_start": []string{"2023-07-01T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":   []*float64{ptr(100)},
+						"address":  "123 Main St",
+						"meter_id": 1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Error: Fail in strict mode when a meter could not be fetched",
+			meterIDs:   []int{1, 2},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			strict:     true,
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1, 2}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{}, errors.New("database is locked"))
+				return repoMock
+			},
+			expectedError: errors.New("failed to get consumption for meters: 1 (consumption_unavailable), 2 (consumption_unavailable)"),
+		},
+		{
+			name:       "Error: Invalid metric",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			metrics:    []string{"net", "carbon"},
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("invalid metric: carbon"),
+		},
+		{
+			name:       "Success: Get quarter hourly consumption data",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-04",
+			kindPeriod: "quarter_hourly",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:05:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:14:59+00")
+				date3, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:15:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-05", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+					{ID: "3", MeterID: 1, ActiveEnergy: 5, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date3},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       clockLabels(time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), 15*time.Minute),
+				"period_start": clockStarts(time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), 15*time.Minute),
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              sparse(96, map[int]float64{40: 30, 41: 5}),
+						"reactive_inductive":  sparse(96, map[int]float64{40: 3, 41: 1}),
+						"reactive_capacitive": sparse(96, map[int]float64{40: 0, 41: 0}),
+						"exported":            sparse(96, map[int]float64{40: 0, 41: 0}),
+						"power_factor":        sparse(96, map[int]float64{40: 0.995, 41: 0.9806}),
+						"apparent":            sparse(96, map[int]float64{40: math.Hypot(30, 3), 41: math.Hypot(5, 1)}),
+						"reactive_penalty":    sparseFlags(96, map[int]bool{40: false, 41: false}),
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Get hourly consumption data",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-04",
+			kindPeriod: "hourly",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:05:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:59:59+00")
+				date3, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 23:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-05", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+					{ID: "3", MeterID: 1, ActiveEnergy: 5, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date3},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       clockLabels(time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), time.Hour),
+				"period_start": clockStarts(time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), time.Hour),
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              sparse(24, map[int]float64{10: 30, 23: 5}),
+						"reactive_inductive":  sparse(24, map[int]float64{10: 3, 23: 1}),
+						"reactive_capacitive": sparse(24, map[int]float64{10: 0, 23: 0}),
+						"exported":            sparse(24, map[int]float64{10: 0, 23: 0}),
+						"power_factor":        sparse(24, map[int]float64{10: 0.995, 23: 0.9806}),
+						"apparent":            sparse(24, map[int]float64{10: math.Hypot(30, 3), 23: math.Hypot(5, 1)}),
+						"reactive_penalty":    sparseFlags(24, map[int]bool{10: false, 23: false}),
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Get ISO weekly consumption data across years",
+			meterIDs:   []int{1},
+			startDate:  "2023-12-30",
+			endDate:    "2024-01-02",
+			kindPeriod: "weekly",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-12-31 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2024-01-01 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-12-30", time.UTC), day("2024-01-03", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"2023-W52", "2024-W01"},
+				"period_start": []string{"2023-12-25T00:00:00Z", "2024-01-01T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(10), ptr(20)},
+						"reactive_inductive":  []*float64{ptr(1), ptr(2)},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0)},
+						"exported":            []*float64{ptr(0), ptr(0)},
+						"power_factor":        []*float64{ptr(0.995), ptr(0.995)},
+						"apparent":            []*float64{ptr(math.Hypot(10, 1)), ptr(math.Hypot(20, 2))},
+						"reactive_penalty":    []*bool{flag(false), flag(false)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:            "Success: Get fiscal quarterly consumption data",
+			meterIDs:        []int{1},
+			startDate:       "2023-06-01",
+			endDate:         "2023-10-31",
+			kindPeriod:      "quarterly",
+			fiscalYearStart: "7",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-06-15 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+				date3, _ := time.Parse("2006-01-02 15:04:05-07", "2023-10-02 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-06-01", time.UTC), day("2023-11-01", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+					{ID: "3", MeterID: 1, ActiveEnergy: 30, ReactiveInductive: 3, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date3},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"FY2023 Q4", "FY2024 Q1", "FY2024 Q2"},
+				"period_start": []string{"2023-04-01T00:00:00Z", "2023-07-01T00:00:00Z", "2023-10-01T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(10), ptr(20), ptr(30)},
+						"reactive_inductive":  []*float64{ptr(1), ptr(2), ptr(3)},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0), ptr(0)},
+						"exported":            []*float64{ptr(0), ptr(0), ptr(0)},
+						"power_factor":        []*float64{ptr(0.995), ptr(0.995), ptr(0.995)},
+						"apparent":            []*float64{ptr(math.Hypot(10, 1)), ptr(math.Hypot(20, 2)), ptr(math.Hypot(30, 3))},
+						"reactive_penalty":    []*bool{flag(false), flag(false), flag(false)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Get yearly consumption data",
+			meterIDs:   []int{1},
+			startDate:  "2022-12-01",
+			endDate:    "2023-01-31",
+			kindPeriod: "yearly",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2022-12-31 10:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-01-01 10:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2022-12-01", time.UTC), day("2023-02-01", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"2022", "2023"},
+				"period_start": []string{"2022-01-01T00:00:00Z", "2023-01-01T00:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(10), ptr(20)},
+						"reactive_inductive":  []*float64{ptr(1), ptr(2)},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0)},
+						"exported":            []*float64{ptr(0), ptr(0)},
+						"power_factor":        []*float64{ptr(0.995), ptr(0.995)},
+						"apparent":            []*float64{ptr(math.Hypot(10, 1)), ptr(math.Hypot(20, 2))},
+						"reactive_penalty":    []*bool{flag(false), flag(false)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Get consumption data by fixed interval",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-04",
+			kindPeriod: "interval",
+			step:       "6h",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 05:59:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 13:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-05", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 4 2023 00:00", "Jul 4 2023 06:00", "Jul 4 2023 12:00", "Jul 4 2023 18:00"},
+				"period_start": []string{"2023-07-04T00:00:00Z", "2023-07-04T06:00:00Z", "2023-07-04T12:00:00Z", "2023-07-04T18:00:00Z"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(10), nil, ptr(20), nil},
+						"reactive_inductive":  []*float64{ptr(1), nil, ptr(2), nil},
+						"reactive_capacitive": []*float64{ptr(0), nil, ptr(0), nil},
+						"exported":            []*float64{ptr(0), nil, ptr(0), nil},
+						"power_factor":        []*float64{ptr(0.995), nil, ptr(0.995), nil},
+						"apparent":            []*float64{ptr(math.Hypot(10, 1)), nil, ptr(math.Hypot(20, 2)), nil},
+						"reactive_penalty":    []*bool{flag(false), nil, flag(false), nil},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Interval periods across a DST transition",
+			meterIDs:   []int{1},
+			startDate:  "2023-03-26",
+			endDate:    "2023-03-26",
+			kindPeriod: "interval",
+			step:       "6h",
+			timezone:   "Europe/Madrid",
+			metrics:    []string{"active"},
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				// 01:30 CET y 03:30 CEST caen en el mismo periodo local de 00:00 a 06:00.
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-03-26 00:30:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-03-26 01:30:00+00")
+				date3, _ := time.Parse("2006-01-02 15:04:05-07", "2023-03-26 04:30:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-03-26", madrid), day("2023-03-27", madrid), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, Date: date2},
+					{ID: "3", MeterID: 1, ActiveEnergy: 30, Date: date3},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Mar 26 2023 00:00", "Mar 26 2023 06:00", "Mar 26 2023 12:00", "Mar 26 2023 18:00"},
+				"period_start": []string{"2023-03-26T00:00:00+01:00", "2023-03-26T06:00:00+02:00", "2023-03-26T12:00:00+02:00", "2023-03-26T18:00:00+02:00"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":   []*float64{ptr(30), ptr(30), nil, nil},
+						"address":  "123 Main St",
+						"meter_id": 1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Error: Interval without step",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "interval",
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("step is required for kind_period interval"),
+		},
+		{
+			name:       "Error: Invalid step",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "interval",
+			step:       "30s",
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("invalid step: 30s"),
+		},
+		{
+			name:            "Error: Invalid fiscal year start",
+			meterIDs:        []int{1},
+			startDate:       "2023-07-01",
+			endDate:         "2023-07-31",
+			kindPeriod:      "quarterly",
+			fiscalYearStart: "13",
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("invalid fiscal_year_start: 13"),
+		},
+		{
+			name:       "Error: Invalid week start",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "weekly",
+			weekStart:  "friday",
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("invalid week_start: friday"),
+		},
+		{
+			name:       "Success: Bucket days at local midnight",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-04",
+			endDate:    "2023-07-05",
+			kindPeriod: "daily",
+			timezone:   "America/Bogota",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-05 03:00:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-05 05:00:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", bogota), day("2023-07-06", bogota), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       []string{"Jul 4", "Jul 5"},
+				"period_start": []string{"2023-07-04T00:00:00-05:00", "2023-07-05T00:00:00-05:00"},
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              []*float64{ptr(10), ptr(20)},
+						"reactive_inductive":  []*float64{ptr(1), ptr(2)},
+						"reactive_capacitive": []*float64{ptr(0), ptr(0)},
+						"exported":            []*float64{ptr(0), ptr(0)},
+						"power_factor":        []*float64{ptr(0.995), ptr(0.995)},
+						"apparent":            []*float64{ptr(math.Hypot(10, 1)), ptr(math.Hypot(20, 2))},
+						"reactive_penalty":    []*bool{flag(false), flag(false)},
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Success: Hourly periods across a DST transition",
+			meterIDs:   []int{1},
+			startDate:  "2023-11-05",
+			endDate:    "2023-11-05",
+			kindPeriod: "hourly",
+			timezone:   "America/New_York",
+			mockAddress: func() AddressServiceInterface {
+				addressMock := new(MockAddressService)
+				addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+				return addressMock
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				repoMock := new(MockRepository)
+				// 01:30 EDT y 01:30 EST: la misma hora local en dos periodos distintos.
+				date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-11-05 05:30:00+00")
+				date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-11-05 06:30:00+00")
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-11-05", newYork), day("2023-11-06", newYork), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+				}, nil)
+				return repoMock
+			},
+			expectedResults: map[string]interface{}{
+				"errors":       []MeterError{},
+				"period":       clockLabels(time.Date(2023, 11, 5, 0, 0, 0, 0, newYork), time.Hour),
+				"period_start": clockStarts(time.Date(2023, 11, 5, 0, 0, 0, 0, newYork), time.Hour),
+				"data_graph": []map[string]interface{}{
+					{
+						"active":              sparse(25, map[int]float64{1: 10, 2: 20}),
+						"reactive_inductive":  sparse(25, map[int]float64{1: 1, 2: 2}),
+						"reactive_capacitive": sparse(25, map[int]float64{1: 0, 2: 0}),
+						"exported":            sparse(25, map[int]float64{1: 0, 2: 0}),
+						"power_factor":        sparse(25, map[int]float64{1: 0.995, 2: 0.995}),
+						"apparent":            sparse(25, map[int]float64{1: math.Hypot(10, 1), 2: math.Hypot(20, 2)}),
+						"reactive_penalty":    sparseFlags(25, map[int]bool{1: false, 2: false}),
+						"address":             "123 Main St",
+						"meter_id":            1,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "Error: Invalid timezone",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			timezone:   "Mars/Olympus",
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("invalid tz: Mars/Olympus"),
+		},
+		{
+			name:       "Error: Invalid fill",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			fill:       "previous",
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("invalid fill: previous"),
+		},
+		{
+			name:       "Error: Invalid reducer",
+			meterIDs:   []int{1},
+			startDate:  "2023-07-01",
+			endDate:    "2023-07-31",
+			kindPeriod: "monthly",
+			reducer:    "median",
+			mockAddress: func() AddressServiceInterface {
+				return new(MockAddressService)
+			},
+			mockRepository: func() repository.ConsumptionRepositoryInterface {
+				return new(MockRepository)
+			},
+			expectedError: errors.New("invalid reducer: median"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addressService := tt.mockAddress()
+			repo := tt.mockRepository()
+			service := NewConsumptionService(addressService, repo, registeredMeters(tt.meterIDs, tt.cumulative), aggregate.NewDefaultRegistry())
+
+			results, err := service.GetConsumptionByPeriod(context.Background(), ConsumptionQuery{
+				MeterIDs:        tt.meterIDs,
+				StartDate:       tt.startDate,
+				EndDate:         tt.endDate,
+				KindPeriod:      tt.kindPeriod,
+				Reducer:         tt.reducer,
+				Fill:            tt.fill,
+				Timezone:        tt.timezone,
+				WeekStart:       tt.weekStart,
+				Step:            tt.step,
+				FiscalYearStart: tt.fiscalYearStart,
+				Metrics:         tt.metrics,
+				Strict:          tt.strict,
+			})
+
+			if tt.expectedError != nil {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResults, results)
+			}
+		})
+	}
+}
+
+type fixedAggregationStrategy struct{}
+
+func (f *fixedAggregationStrategy) Truncate(date time.Time) time.Time {
+	return time.Date(2000, 1, 1, 0, 0, 0, 0, date.Location())
+}
+
+func (f *fixedAggregationStrategy) Next(start time.Time) time.Time {
+	return time.Date(9999, 1, 1, 0, 0, 0, 0, start.Location())
+}
+
+func (f *fixedAggregationStrategy) Label(start time.Time) string {
+	return "all"
+}
+
+func TestConsumptionService_CustomStrategy(t *testing.T) {
+	registry := aggregate.NewDefaultRegistry()
+	err := registry.Register(aggregate.StrategyInfo{Name: "all", Description: "Todo el rango"}, func(aggregate.Options) (aggregate.AggregationStrategy, error) {
+		return &fixedAggregationStrategy{}, nil
+	})
+	assert.NoError(t, err)
+
+	err = registry.Register(aggregate.StrategyInfo{Name: "daily"}, func(aggregate.Options) (aggregate.AggregationStrategy, error) {
+		return &fixedAggregationStrategy{}, nil
+	})
+	assert.EqualError(t, err, "strategy already registered: daily")
+
+	addressMock := new(MockAddressService)
+	addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+	repoMock := new(MockRepository)
+	date1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+	date2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-20 10:00:00+00")
+	repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{
+		{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date1},
+		{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, ReactiveCapacitive: 0, ExportedEnergy: 0, Date: date2},
+	}, nil)
+
+	service := NewConsumptionService(addressMock, repoMock, registeredMeters([]int{1}, nil), registry)
+
+	kinds := service.ListPeriodKinds()
+	assert.Equal(t, "quarter_hourly", kinds[0].Name)
+	assert.Equal(t, aggregate.StrategyInfo{Name: "all", Description: "Todo el rango", Parameters: []string{}}, kinds[len(kinds)-1])
+	assert.Equal(t, []string{"tz", "week_start"}, service.ListPeriodParameters())
+
+	results, err := service.GetConsumptionByPeriod(context.Background(), ConsumptionQuery{
+		MeterIDs:   []int{1},
+		StartDate:  "2023-07-01",
+		EndDate:    "2023-07-31",
+		KindPeriod: "all",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"all"}, results["period"])
+	assert.Equal(t, []*float64{ptr(30)}, results["data_graph"].([]map[string]interface{})[0]["active"])
+}
+
+func TestConsumptionService_GetConsumptionByPeriod_SQLAggregation(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	july, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-01 00:00:00+00")
+	reading1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+	reading2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-20 10:00:00+00")
+
+	tests := []struct {
+		name             string
+		reducer          string
+		timezone         string
+		metrics          []string
+		cumulativeMeters []int
+		mockRepository   func(repoMock *MockAggregatingRepository)
+		expectedActive   []*float64
+	}{
+		{
+			name: "Sum is grouped by the database",
+			mockRepository: func(repoMock *MockAggregatingRepository) {
+				repoMock.On("GetAggregatedConsumption", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), aggregate.UnitMonth, repository.AggregateSum, mock.Anything).Return([]model.Consumption{
+					{MeterID: 1, ActiveEnergy: 30, ReactiveInductive: 3, Date: july},
+				}, nil)
+			},
+			expectedActive: []*float64{ptr(30)},
+		},
+		{
+			name:    "Mean of raw series is grouped by the database",
+			reducer: "mean",
+			metrics: []string{MetricActive},
+			mockRepository: func(repoMock *MockAggregatingRepository) {
+				repoMock.On("GetAggregatedConsumption", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), aggregate.UnitMonth, repository.AggregateMean, []string{repository.ColumnActiveEnergy}).Return([]model.Consumption{
+					{MeterID: 1, ActiveEnergy: 15, Date: july},
+				}, nil)
+			},
+			expectedActive: []*float64{ptr(15)},
+		},
+		{
+			name:    "Mean with derived series is aggregated in memory",
+			reducer: "mean",
+			mockRepository: func(repoMock *MockAggregatingRepository) {
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, Date: reading1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, Date: reading2},
+				}, nil)
+			},
+			expectedActive: []*float64{ptr(15)},
+		},
+		{
+			name:     "Non-UTC timezone is aggregated in memory",
+			timezone: "America/New_York",
+			mockRepository: func(repoMock *MockAggregatingRepository) {
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-01", newYork), day("2023-08-01", newYork), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, Date: reading1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, Date: reading2},
+				}, nil)
+			},
+			expectedActive: []*float64{ptr(30)},
+		},
+		{
+			name:             "Cumulative meters are aggregated in memory",
+			cumulativeMeters: []int{1},
+			mockRepository: func(repoMock *MockAggregatingRepository) {
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 10, ReactiveInductive: 1, Date: reading1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 20, ReactiveInductive: 2, Date: reading2},
+				}, nil)
+				repoMock.On("GetLastReadingsBefore", mock.Anything, []int{1}, day("2023-07-01", time.UTC), mock.Anything).Return([]model.Consumption{}, nil)
+			},
+			expectedActive: []*float64{ptr(10)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addressMock := new(MockAddressService)
+			addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+			repoMock := new(MockAggregatingRepository)
+			tt.mockRepository(repoMock)
+
+			service := NewConsumptionService(addressMock, repoMock, registeredMeters([]int{1}, tt.cumulativeMeters), aggregate.NewDefaultRegistry())
+
+			results, err := service.GetConsumptionByPeriod(context.Background(), ConsumptionQuery{
+				MeterIDs:   []int{1},
+				StartDate:  "2023-07-01",
+				EndDate:    "2023-07-31",
+				KindPeriod: "monthly",
+				Reducer:    tt.reducer,
+				Timezone:   tt.timezone,
+				Metrics:    tt.metrics,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Jul 2023"}, results["period"])
+			assert.Equal(t, tt.expectedActive, results["data_graph"].([]map[string]interface{})[0]["active"])
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConsumptionService_GetConsumptionByPeriod_RelativeRange(t *testing.T) {
+	now := time.Date(2023, 7, 4, 10, 20, 0, 0, time.UTC)
+	reading := time.Date(2023, 7, 4, 8, 15, 0, 0, time.UTC)
+
+	addressMock := new(MockAddressService)
+	addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+	repoMock := new(MockRepository)
+	repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, now.Add(-3*time.Hour), now, mock.Anything).Return([]model.Consumption{
+		{ID: "1", MeterID: 1, ActiveEnergy: 10, Date: reading},
+	}, nil)
+
+	service := NewConsumptionService(addressMock, repoMock, registeredMeters([]int{1}, nil), aggregate.NewDefaultRegistry())
+	service.now = func() time.Time { return now }
+
+	results, err := service.GetConsumptionByPeriod(context.Background(), ConsumptionQuery{
+		MeterIDs:   []int{1},
+		Range:      "last_3h",
+		KindPeriod: "hourly",
+		Metrics:    []string{MetricActive},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Jul 4 07:00", "Jul 4 08:00", "Jul 4 09:00", "Jul 4 10:00"}, results["period"])
+	assert.Equal(t, []*float64{nil, ptr(10), nil, nil}, results["data_graph"].([]map[string]interface{})[0]["active"])
+	repoMock.AssertExpectations(t)
+}
+
+func TestConsumptionService_GetConsumptionByPeriod_IgnoresUndeclaredParameters(t *testing.T) {
+	addressMock := new(MockAddressService)
+	addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil)
+	repoMock := new(MockRepository)
+	repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-04", time.UTC), day("2023-07-05", time.UTC), mock.Anything).Return([]model.Consumption{}, nil)
+
+	service := NewConsumptionService(addressMock, repoMock, registeredMeters([]int{1}, nil), aggregate.NewDefaultRegistry())
+	results, err := service.GetConsumptionByPeriod(context.Background(), ConsumptionQuery{
+		MeterIDs:        []int{1},
+		StartDate:       "2023-07-04",
+		EndDate:         "2023-07-04",
+		KindPeriod:      "daily",
+		Step:            "not-a-duration",
+		FiscalYearStart: "13",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Jul 4"}, results["period"])
+	repoMock.AssertExpectations(t)
+}
+
+func TestConsumptionService_GetConsumptionByPeriod_TooManyPeriods(t *testing.T) {
+	tests := []struct {
+		name  string
+		query ConsumptionQuery
+	}{
+		{
+			name:  "One-minute intervals over a year",
+			query: ConsumptionQuery{MeterIDs: []int{1}, StartDate: "2023-01-01", EndDate: "2023-12-31", KindPeriod: "interval", Step: "1m"},
+		},
+		{
+			name:  "Quarter hours over a huge relative range",
+			query: ConsumptionQuery{MeterIDs: []int{1}, Range: "last_100000w", KindPeriod: "quarter_hourly"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoMock := new(MockRepository)
+			service := NewConsumptionService(new(MockAddressService), repoMock, registeredMeters([]int{1}, nil), aggregate.NewDefaultRegistry())
+
+			results, err := service.GetConsumptionByPeriod(context.Background(), tt.query)
+
+			assert.Nil(t, results)
+			assert.EqualError(t, err, "too many periods: the range has more than 10000 periods")
+			repoMock.AssertNotCalled(t, "GetConsumptionByMeters", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestConsumptionService_GetConsumptionByPeriod_Meters(t *testing.T) {
+	reading1, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-04 10:00:00+00")
+	reading2, _ := time.Parse("2006-01-02 15:04:05-07", "2023-07-20 10:00:00+00")
+
+	tests := []struct {
+		name           string
+		meterIDs       []int
+		mockMeters     func(meterMock *MockMeterRepository)
+		mockRepository func(repoMock *MockAggregatingRepository)
+		expectedActive []*float64
+		expectedErrors []MeterError
+		expectedError  error
+	}{
+		{
+			name:     "Unknown meters are rejected",
+			meterIDs: []int{1, 4, 5},
+			mockMeters: func(meterMock *MockMeterRepository) {
+				meterMock.On("GetMetersByIDs", mock.Anything, []int{1, 4, 5}).Return([]model.Meter{{ID: 1, Multiplier: 1}}, nil)
+			},
+			mockRepository: func(repoMock *MockAggregatingRepository) {},
+			expectedError:  errors.New("meters not found: 4, 5"),
+		},
+		{
+			name:     "Meter lookup failure is reported per meter",
+			meterIDs: []int{1},
+			mockMeters: func(meterMock *MockMeterRepository) {
+				meterMock.On("GetMetersByIDs", mock.Anything, []int{1}).Return([]model.Meter(nil), errors.New("database is locked"))
+			},
+			mockRepository: func(repoMock *MockAggregatingRepository) {},
+			expectedErrors: []MeterError{
+				{MeterID: 1, Code: ErrCodeConsumptionUnavailable, Reason: "consumption data is temporarily unavailable"},
+			},
+		},
+		{
+			name:     "Multiplier scales readings and is aggregated in memory",
+			meterIDs: []int{1},
+			mockMeters: func(meterMock *MockMeterRepository) {
+				meterMock.On("GetMetersByIDs", mock.Anything, []int{1}).Return([]model.Meter{{ID: 1, Multiplier: 40}}, nil)
+			},
+			mockRepository: func(repoMock *MockAggregatingRepository) {
+				repoMock.On("GetConsumptionByMeters", mock.Anything, []int{1}, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{
+					{ID: "1", MeterID: 1, ActiveEnergy: 1.5, Date: reading1},
+					{ID: "2", MeterID: 1, ActiveEnergy: 2, Date: reading2},
+				}, nil)
+			},
+			expectedActive: []*float64{ptr(140)},
+			expectedErrors: []MeterError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addressMock := new(MockAddressService)
+			addressMock.On("GetAddress", mock.Anything, 1).Return(&adapter.Address{Address: "123 Main St"}, nil).Maybe()
+			meterMock := new(MockMeterRepository)
+			tt.mockMeters(meterMock)
+			repoMock := new(MockAggregatingRepository)
+			tt.mockRepository(repoMock)
+
+			service := NewConsumptionService(addressMock, repoMock, meterMock, aggregate.NewDefaultRegistry())
+			results, err := service.GetConsumptionByPeriod(context.Background(), ConsumptionQuery{
+				MeterIDs:   tt.meterIDs,
+				StartDate:  "2023-07-01",
+				EndDate:    "2023-07-31",
+				KindPeriod: "monthly",
+				Metrics:    []string{MetricActive},
+			})
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				var notFound *MeterNotFoundError
+				assert.ErrorAs(t, err, &notFound)
+				repoMock.AssertNotCalled(t, "GetConsumptionByMeters", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedErrors, results["errors"])
+			if tt.expectedActive != nil {
+				assert.Equal(t, tt.expectedActive, results["data_graph"].([]map[string]interface{})[0]["active"])
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConsumptionService_GetConsumptionByPeriod_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repoMock := new(MockRepository)
+	meterMock := new(MockMeterRepository)
+	meterMock.On("GetMetersByIDs", ctx, []int{1}).Return([]model.Meter(nil), context.Canceled)
+
+	service := NewConsumptionService(new(MockAddressService), repoMock, meterMock, aggregate.NewDefaultRegistry())
+
+	_, err := service.GetConsumptionByPeriod(ctx, ConsumptionQuery{
+		MeterIDs:   []int{1},
+		StartDate:  "2023-07-01",
+		EndDate:    "2023-07-31",
+		KindPeriod: "monthly",
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	meterMock.AssertExpectations(t)
+	repoMock.AssertNotCalled(t, "GetConsumptionByMeters", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// concurrencyAddressService registra cuántas búsquedas de dirección se ejecutan a la vez.
+type concurrencyAddressService struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (r *concurrencyAddressService) GetAddress(ctx context.Context, meterID int) (*adapter.Address, error) {
+	r.mu.Lock()
+	r.running++
+	if r.running > r.peak {
+		r.peak = r.running
+	}
+	r.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	r.mu.Lock()
+	r.running--
+	r.mu.Unlock()
+	return &adapter.Address{Address: "123 Main St"}, nil
+}
+
+func TestConsumptionService_GetConsumptionByPeriod_BoundedConcurrency(t *testing.T) {
+	var meterIDs []int
+	for meterID := 1; meterID <= 20; meterID++ {
+		meterIDs = append(meterIDs, meterID)
+	}
+
+	repoMock := new(MockRepository)
+	repoMock.On("GetConsumptionByMeters", mock.Anything, meterIDs, day("2023-07-01", time.UTC), day("2023-08-01", time.UTC), mock.Anything).Return([]model.Consumption{}, nil).Once()
+	addressService := &concurrencyAddressService{}
+
+	service := NewConsumptionService(addressService, repoMock, registeredMeters(meterIDs, nil), aggregate.NewDefaultRegistry())
+	service.SetMaxConcurrency(3)
+
+	results, err := service.GetConsumptionByPeriod(context.Background(), ConsumptionQuery{
+		MeterIDs:   meterIDs,
+		StartDate:  "2023-07-01",
+		EndDate:    "2023-07-31",
+		KindPeriod: "monthly",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, results["data_graph"], 20)
+	assert.LessOrEqual(t, addressService.peak, 3)
+	assert.Greater(t, addressService.peak, 1)
+	repoMock.AssertExpectations(t)
 }
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/aggregate"
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+)
+
+// Series que puede devolver la consulta de consumo por periodo.
+const (
+	MetricActive             = "active"
+	MetricReactiveInductive  = "reactive_inductive"
+	MetricReactiveCapacitive = "reactive_capacitive"
+	MetricExported           = "exported"
+	MetricPowerFactor        = "power_factor"
+	MetricApparent           = "apparent"
+	MetricReactivePenalty    = "reactive_penalty"
+	MetricInductivePenalty   = "inductive_penalty"
+	MetricCapacitivePenalty  = "capacitive_penalty"
+	MetricNet                = "net"
+	MetricExportRatio        = "export_ratio"
+	MetricExportCoverage     = "export_coverage"
+)
+
+// defaultMetrics son las series que se devuelven cuando la consulta no indica ninguna.
+var defaultMetrics = []string{
+	MetricActive,
+	MetricReactiveInductive,
+	MetricReactiveCapacitive,
+	MetricExported,
+	MetricPowerFactor,
+	MetricApparent,
+	MetricReactivePenalty,
+}
+
+// rawMetrics son las series que salen directamente de una columna, sin derivarse de los totales.
+var rawMetrics = map[string]bool{
+	MetricActive:             true,
+	MetricReactiveInductive:  true,
+	MetricReactiveCapacitive: true,
+	MetricExported:           true,
+}
+
+// metricColumns son las columnas de consumo que necesita cada serie.
+var metricColumns = map[string][]string{
+	MetricActive:             {repository.ColumnActiveEnergy},
+	MetricReactiveInductive:  {repository.ColumnReactiveInductive},
+	MetricReactiveCapacitive: {repository.ColumnReactiveCapacitive},
+	MetricExported:           {repository.ColumnExportedEnergy},
+	MetricPowerFactor:        {repository.ColumnActiveEnergy, repository.ColumnReactiveInductive, repository.ColumnReactiveCapacitive},
+	MetricApparent:           {repository.ColumnActiveEnergy, repository.ColumnReactiveInductive, repository.ColumnReactiveCapacitive},
+	MetricReactivePenalty:    {repository.ColumnActiveEnergy, repository.ColumnReactiveInductive, repository.ColumnReactiveCapacitive},
+	MetricInductivePenalty:   {repository.ColumnActiveEnergy, repository.ColumnReactiveInductive},
+	MetricCapacitivePenalty:  {repository.ColumnReactiveCapacitive},
+	MetricNet:                {repository.ColumnActiveEnergy, repository.ColumnExportedEnergy},
+	MetricExportRatio:        {repository.ColumnActiveEnergy, repository.ColumnExportedEnergy},
+	MetricExportCoverage:     {repository.ColumnActiveEnergy, repository.ColumnExportedEnergy},
+}
+
+// metricFields valida las series pedidas y devuelve las columnas que hay que
+// cargar para calcularlas, sin repetir y en el orden de la tabla.
+func metricFields(metrics []string) ([]string, error) {
+	needed := make(map[string]bool)
+	for _, metric := range metrics {
+		columns, exists := metricColumns[metric]
+		if !exists {
+			return nil, fmt.Errorf("invalid metric: %s", metric)
+		}
+		for _, column := range columns {
+			needed[column] = true
+		}
+	}
+
+	var fields []string
+	for _, column := range repository.EnergyColumns {
+		if needed[column] {
+			fields = append(fields, column)
+		}
+	}
+	return fields, nil
+}
+
+// buildSeries arma las series pedidas a partir de los periodos reducidos y de
+// sus totales, ambos alineados al eje común.
+func buildSeries(aligned, sums []*model.AggregatedConsumption, fill string, metrics []string, penaltyRatio float64) map[string]interface{} {
+	series := make(map[string]interface{}, len(metrics)+2)
+
+	var power map[string]interface{}
+	prosumer := prosumerSeries(sums, fill, metrics)
+
+	for _, metric := range metrics {
+		switch metric {
+		case MetricActive:
+			series[metric] = alignedValues(aligned, fill, func(a *model.AggregatedConsumption) []float64 { return a.ActiveEnergy })
+		case MetricReactiveInductive:
+			series[metric] = alignedValues(aligned, fill, func(a *model.AggregatedConsumption) []float64 { return a.ReactiveInductive })
+		case MetricReactiveCapacitive:
+			series[metric] = alignedValues(aligned, fill, func(a *model.AggregatedConsumption) []float64 { return a.ReactiveCapacitive })
+		case MetricExported:
+			series[metric] = alignedValues(aligned, fill, func(a *model.AggregatedConsumption) []float64 { return a.ExportedEnergy })
+		case MetricPowerFactor, MetricApparent, MetricReactivePenalty, MetricInductivePenalty, MetricCapacitivePenalty:
+			if power == nil {
+				power = powerSeries(sums, fill, penaltyRatio)
+			}
+			series[metric] = power[metric]
+		default:
+			if values, exists := prosumer[metric]; exists {
+				series[metric] = values
+			}
+		}
+	}
+
+	return series
+}
+
+// alignedValues extrae una serie reducida sobre el eje de periodos, rellenando
+// los periodos sin lecturas según el modo indicado.
+func alignedValues(aligned []*model.AggregatedConsumption, fill string, value func(*model.AggregatedConsumption) []float64) []*float64 {
+	values := make([]*float64, len(aligned))
+	for i, aggData := range aligned {
+		switch {
+		case aggData != nil:
+			v := value(aggData)[0]
+			values[i] = &v
+		case fill == FillZero:
+			zero := 0.0
+			values[i] = &zero
+		}
+	}
+	return values
+}
+
+// powerSeries calcula el factor de potencia, la energía aparente y las marcas
+// de penalización por energía reactiva a partir de los totales de cada periodo.
+func powerSeries(sums []*model.AggregatedConsumption, fill string, penaltyRatio float64) map[string]interface{} {
+	powerFactor := make([]*float64, len(sums))
+	apparent := make([]*float64, len(sums))
+	penalty := make([]*bool, len(sums))
+	inductive := make([]*bool, len(sums))
+	capacitive := make([]*bool, len(sums))
+
+	for i, aggData := range sums {
+		if aggData == nil {
+			if fill == FillZero {
+				zero, noPenalty := 0.0, false
+				apparent[i], penalty[i], inductive[i], capacitive[i] = &zero, &noPenalty, &noPenalty, &noPenalty
+			}
+			continue
+		}
+
+		quality := aggregate.AnalyzePower(aggData.ActiveEnergy[0], aggData.ReactiveInductive[0], aggData.ReactiveCapacitive[0], penaltyRatio)
+		powerFactor[i] = quality.PowerFactor
+		apparent[i] = &quality.ApparentEnergy
+		penalty[i] = &quality.ReactivePenalty
+		inductive[i] = &quality.InductivePenalty
+		capacitive[i] = &quality.CapacitivePenalty
+	}
+
+	return map[string]interface{}{
+		MetricPowerFactor:       powerFactor,
+		MetricApparent:          apparent,
+		MetricReactivePenalty:   penalty,
+		MetricInductivePenalty:  inductive,
+		MetricCapacitivePenalty: capacitive,
+	}
+}
+
+// prosumerSeries calcula las series de balance importación/exportación pedidas
+// a partir de los totales de cada periodo.
+func prosumerSeries(sums []*model.AggregatedConsumption, fill string, metrics []string) map[string][]*float64 {
+	series := make(map[string][]*float64)
+	for _, metric := range metrics {
+		if metric == MetricNet || metric == MetricExportRatio || metric == MetricExportCoverage {
+			series[metric] = make([]*float64, len(sums))
+		}
+	}
+
+	for i, aggData := range sums {
+		if aggData == nil {
+			if values, exists := series[MetricNet]; exists && fill == FillZero {
+				zero := 0.0
+				values[i] = &zero
+			}
+			continue
+		}
+
+		balance := aggregate.AnalyzeProsumer(aggData.ActiveEnergy[0], aggData.ExportedEnergy[0])
+		for metric, values := range series {
+			switch metric {
+			case MetricNet:
+				values[i] = &balance.NetEnergy
+			case MetricExportRatio:
+				values[i] = balance.ExportRatio
+			case MetricExportCoverage:
+				values[i] = balance.ExportCoverage
+			}
+		}
+	}
+
+	return series
+}
@@ -3,94 +3,453 @@ package services
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/SaidHernandez/bia-comsumtion/business/aggregate"
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
 	"github.com/SaidHernandez/bia-comsumtion/business/repository"
 )
 
 type ConsumptionService struct {
 	addressService AddressServiceInterface
 	repository     repository.ConsumptionRepositoryInterface
+	meters         repository.MeterRepositoryInterface
+	strategies     *aggregate.Registry
+	penaltyRatio   float64
+	maxConcurrency int
+	maxPeriods     int
+	now            func() time.Time
 }
 
-func NewConsumptionService(addressService AddressServiceInterface, repository repository.ConsumptionRepositoryInterface) *ConsumptionService {
+// DefaultMaxConcurrency es el número máximo de medidores que se consultan a la vez.
+const DefaultMaxConcurrency = 8
+
+// DefaultMaxPeriods es el número máximo de periodos que puede devolver una consulta.
+const DefaultMaxPeriods = 10000
+
+func NewConsumptionService(addressService AddressServiceInterface, repository repository.ConsumptionRepositoryInterface, meters repository.MeterRepositoryInterface, strategies *aggregate.Registry) *ConsumptionService {
 	return &ConsumptionService{
 		addressService: addressService,
 		repository:     repository,
+		meters:         meters,
+		strategies:     strategies,
+		penaltyRatio:   aggregate.DefaultReactivePenaltyRatio,
+		maxConcurrency: DefaultMaxConcurrency,
+		maxPeriods:     DefaultMaxPeriods,
+		now:            time.Now,
 	}
 }
 
-func (service *ConsumptionService) GetConsumptionByPeriod(ctx context.Context, meterIDs []int, startDate, endDate, kindPeriod string) (map[string]interface{}, error) {
-	strategies := map[string]aggregate.AggregationStrategy{
-		"monthly": &aggregate.MonthlyAggregationStrategy{},
-		"weekly":  &aggregate.WeeklyAggregationStrategy{},
-		"daily":   &aggregate.DailyAggregationStrategy{},
+// SetMaxConcurrency limita cuántos medidores se consultan a la vez en una
+// misma solicitud. Un valor menor o igual a cero elimina el límite.
+func (service *ConsumptionService) SetMaxConcurrency(limit int) {
+	service.maxConcurrency = limit
+}
+
+// SetMaxPeriods limita cuántos periodos puede devolver una consulta. Un valor
+// menor o igual a cero elimina el límite.
+func (service *ConsumptionService) SetMaxPeriods(limit int) {
+	service.maxPeriods = limit
+}
+
+// SetReactivePenaltyRatio define la proporción de energía reactiva inductiva sobre la activa
+// a partir de la cual un periodo se marca como penalizable.
+func (service *ConsumptionService) SetReactivePenaltyRatio(ratio float64) {
+	service.penaltyRatio = ratio
+}
+
+// ConsumptionQuery agrupa los filtros de una consulta de consumo por periodo.
+type ConsumptionQuery struct {
+	MeterIDs []int
+	// StartDate y EndDate aceptan fechas YYYY-MM-DD (EndDate incluye el día
+	// completo) o instantes RFC3339 (EndDate exclusivo).
+	StartDate string
+	EndDate   string
+	// Range es un rango relativo resuelto en el servidor (last_7d, this_month,
+	// previous_month, ...); si se indica, reemplaza a StartDate y EndDate.
+	Range      string
+	KindPeriod string
+	Reducer    string
+	Fill       string
+	// Timezone es el nombre IANA de la zona usada para los límites de los periodos (UTC por defecto).
+	Timezone string
+	// WeekStart es el día en que empiezan las semanas: monday (ISO-8601, por defecto) o sunday.
+	WeekStart string
+	// Step es la duración de cada periodo para kind_period=interval, p. ej. 6h.
+	Step string
+	// FiscalYearStart es el mes (1-12) en que empieza el año fiscal para los periodos quarterly y yearly.
+	FiscalYearStart string
+	// Metrics son las series a devolver; si está vacío se devuelven defaultMetrics.
+	Metrics []string
+	// Strict hace que la consulta falle si no se pudo obtener algún medidor.
+	Strict bool
+}
+
+const defaultReducer = "sum"
+
+const defaultWeekStart = "monday"
+
+var weekStarts = map[string]time.Weekday{
+	"monday": time.Monday,
+	"sunday": time.Sunday,
+}
+
+var reducers = map[string]aggregate.Reducer{
+	"sum":   &aggregate.SumReducer{},
+	"mean":  &aggregate.MeanReducer{},
+	"min":   &aggregate.MinReducer{},
+	"max":   &aggregate.MaxReducer{},
+	"count": &aggregate.CountReducer{},
+}
+
+// sqlFunctions son los reducers que la base de datos puede aplicar al agrupar.
+var sqlFunctions = map[string]string{
+	"sum":   repository.AggregateSum,
+	"mean":  repository.AggregateMean,
+	"min":   repository.AggregateMin,
+	"max":   repository.AggregateMax,
+	"count": repository.AggregateCount,
+}
+
+// Modos de relleno para los periodos sin lecturas.
+const (
+	FillNull = "null"
+	FillZero = "zero"
+)
+
+// ListPeriodKinds devuelve los tipos de periodo disponibles.
+func (service *ConsumptionService) ListPeriodKinds() []aggregate.StrategyInfo {
+	return service.strategies.List()
+}
+
+// ListPeriodParameters devuelve los parámetros que aplican a todos los tipos de
+// periodo, además de los que declara cada uno. week_start define el inicio de
+// la semana en los rangos relativos, por lo que se valida con cualquier tipo.
+func (service *ConsumptionService) ListPeriodParameters() []string {
+	return []string{"tz", "week_start"}
+}
+
+// aggregationOptions interpreta los parámetros de la consulta que configuran las
+// estrategias. step y fiscal_year_start sólo se validan si la estrategia los
+// declara; week_start se valida siempre porque también define los rangos relativos.
+func aggregationOptions(query ConsumptionQuery, info aggregate.StrategyInfo) (aggregate.Options, error) {
+	weekStartName := query.WeekStart
+	if weekStartName == "" {
+		weekStartName = defaultWeekStart
+	}
+	weekStart, exists := weekStarts[weekStartName]
+	if !exists {
+		return aggregate.Options{}, fmt.Errorf("invalid week_start: %s", query.WeekStart)
 	}
 
-	strategy, exists := strategies[kindPeriod]
+	var step time.Duration
+	if query.Step != "" && info.Accepts("step") {
+		parsed, err := time.ParseDuration(query.Step)
+		if err != nil {
+			return aggregate.Options{}, fmt.Errorf("invalid step: %s", query.Step)
+		}
+		step = parsed
+	}
+
+	fiscalYearStart := time.January
+	if query.FiscalYearStart != "" && info.Accepts("fiscal_year_start") {
+		month, err := strconv.Atoi(query.FiscalYearStart)
+		if err != nil || month < 1 || month > 12 {
+			return aggregate.Options{}, fmt.Errorf("invalid fiscal_year_start: %s", query.FiscalYearStart)
+		}
+		fiscalYearStart = time.Month(month)
+	}
+
+	return aggregate.Options{
+		WeekStart:       weekStart,
+		Step:            step,
+		FiscalYearStart: fiscalYearStart,
+	}, nil
+}
+
+func (service *ConsumptionService) GetConsumptionByPeriod(ctx context.Context, query ConsumptionQuery) (map[string]interface{}, error) {
+	info, err := service.strategies.Info(query.KindPeriod)
+	if err != nil {
+		return nil, err
+	}
+	options, err := aggregationOptions(query, info)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := service.strategies.Build(query.KindPeriod, options)
+	if err != nil {
+		return nil, err
+	}
+
+	reducerName := query.Reducer
+	if reducerName == "" {
+		reducerName = defaultReducer
+	}
+	reducer, exists := reducers[reducerName]
 	if !exists {
-		return nil, fmt.Errorf("invalid kind_period: %s", kindPeriod)
+		return nil, fmt.Errorf("invalid reducer: %s", query.Reducer)
 	}
 
-	var wg sync.WaitGroup
-	var dataGraph []map[string]interface{}
-	var periods []string
-	mu := sync.Mutex{}
+	fill := query.Fill
+	if fill == "" {
+		fill = FillNull
+	}
+	if fill != FillNull && fill != FillZero {
+		return nil, fmt.Errorf("invalid fill: %s", query.Fill)
+	}
 
-	for _, meterID := range meterIDs {
-		wg.Add(1)
-		go func(meterID int) {
-			defer wg.Done()
+	metrics := query.Metrics
+	if len(metrics) == 0 {
+		metrics = defaultMetrics
+	}
+	fields, err := metricFields(metrics)
+	if err != nil {
+		return nil, err
+	}
 
-			consumptions, err := service.repository.GetConsumptionByFilters(meterID, startDate, endDate)
-			if err != nil {
-				fmt.Println("Error fetching data for meterID", meterID, ":", err)
-				return
-			}
+	loc, err := time.LoadLocation(query.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz: %s", query.Timezone)
+	}
 
-			aggregatedData := strategy.Aggregate(consumptions)
+	from, until, err := resolveTimeRange(query, loc, options.WeekStart, service.now())
+	if err != nil {
+		return nil, err
+	}
+	// El límite se comprueba antes de cargar lecturas o construir series.
+	periods, err := aggregate.Periods(strategy, from, until, service.maxPeriods)
+	if err != nil {
+		return nil, err
+	}
 
-			address, err := service.addressService.GetAddress(ctx, meterID)
-			if err != nil {
-				fmt.Println("Error fetching address for meterID", meterID, ":", err)
-				return
+	meters, loadErr := service.meters.GetMetersByIDs(ctx, query.MeterIDs)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	metersByID := make(map[int]model.Meter, len(meters))
+	for _, meter := range meters {
+		metersByID[meter.ID] = meter
+	}
+	if loadErr == nil {
+		var missing []int
+		for _, meterID := range query.MeterIDs {
+			if _, exists := metersByID[meterID]; !exists {
+				missing = append(missing, meterID)
 			}
+		}
+		if len(missing) > 0 {
+			return nil, &MeterNotFoundError{MeterIDs: missing}
+		}
+	}
 
-			var active []float64
-			var reactiveInductive []float64
-			var reactiveCapacitive []float64
-			var exported []float64
-			localPeriods := []string{}
-
-			for _, aggData := range aggregatedData {
-				localPeriods = append(localPeriods, aggData.Period...)
-				active = append(active, aggData.ActiveEnergy...)
-				reactiveInductive = append(reactiveInductive, aggData.ReactiveInductive...)
-				reactiveCapacitive = append(reactiveCapacitive, aggData.ReactiveCapacitive...)
-				exported = append(exported, aggData.ExportedEnergy...)
-			}
+	aggregating, unit, function, pushdown := service.sqlAggregation(strategy, reducerName, metrics, loc, meters)
 
-			mu.Lock()
-			if len(periods) == 0 { // Guardar los periodos solo una vez
-				periods = localPeriods
-			}
-			dataGraph = append(dataGraph, map[string]interface{}{
-				"meter_id":            meterID,
-				"address":             address.Address,
-				"active":              active,
-				"reactive_inductive":  reactiveInductive,
-				"reactive_capacitive": reactiveCapacitive,
-				"exported":            exported,
-			})
-			mu.Unlock()
-		}(meterID)
+	var allConsumptions []model.Consumption
+	switch {
+	case loadErr != nil:
+	case pushdown:
+		allConsumptions, loadErr = aggregating.GetAggregatedConsumption(ctx, query.MeterIDs, from, until, unit, function, fields)
+		// Cada fila ya es el valor reducido de su periodo; sumar un único valor lo deja igual.
+		reducer = &aggregate.SumReducer{}
+	default:
+		allConsumptions, loadErr = service.repository.GetConsumptionByMeters(ctx, query.MeterIDs, from, until, fields)
+		if loadErr == nil {
+			var baselines []model.Consumption
+			baselines, loadErr = service.cumulativeBaselines(ctx, meters, from, fields)
+			allConsumptions = append(baselines, allConsumptions...)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	wg.Wait()
+	consumptionsByMeter := make(map[int][]model.Consumption, len(query.MeterIDs))
+	for _, consumption := range allConsumptions {
+		consumptionsByMeter[consumption.MeterID] = append(consumptionsByMeter[consumption.MeterID], consumption)
+	}
+
+	results := make([]map[string]interface{}, len(query.MeterIDs))
+	failures := make([]*MeterError, len(query.MeterIDs))
+
+	runBounded(ctx, len(query.MeterIDs), service.maxConcurrency, func(i int) {
+		meterID := query.MeterIDs[i]
+
+		if loadErr != nil {
+			failures[i] = newMeterError(meterID, ErrCodeConsumptionUnavailable, loadErr)
+			return
+		}
+
+		consumptions := consumptionsByMeter[meterID]
+		if !pushdown {
+			consumptions = meterReadings(metersByID[meterID], consumptions)
+		}
+
+		aggregatedData := aggregate.Aggregate(strategy, consumptions, loc)
+		aligned := aggregate.Align(aggregate.Reduce(aggregatedData, reducer), periods)
+		sums := aggregate.Align(aggregate.Reduce(aggregatedData, &aggregate.SumReducer{}), periods)
+
+		address, err := service.addressService.GetAddress(ctx, meterID)
+		if err != nil {
+			failures[i] = newMeterError(meterID, ErrCodeAddressUnavailable, err)
+			return
+		}
+
+		result := buildSeries(aligned, sums, fill, metrics, service.penaltyRatio)
+		result["meter_id"] = meterID
+		result["address"] = address.Address
+		results[i] = result
+	})
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dataGraph := []map[string]interface{}{}
+	meterErrors := []MeterError{}
+	for i, result := range results {
+		if result != nil {
+			dataGraph = append(dataGraph, result)
+		}
+		if failures[i] != nil {
+			meterErrors = append(meterErrors, *failures[i])
+		}
+	}
+
+	if query.Strict && len(meterErrors) > 0 {
+		return nil, &PartialFailureError{Errors: meterErrors}
+	}
+
+	// Las etiquetas pueden repetirse (sin año o en el cambio de horario), por lo
+	// que cada periodo también se identifica por su inicio en RFC3339.
+	labels := make([]string, len(periods))
+	starts := make([]string, len(periods))
+	for i, start := range periods {
+		labels[i] = strategy.Label(start)
+		starts[i] = start.Format(time.RFC3339)
+	}
 
 	return map[string]interface{}{
-		"period":     periods,
-		"data_graph": dataGraph,
+		"period":       labels,
+		"period_start": starts,
+		"data_graph":   dataGraph,
+		"errors":       meterErrors,
 	}, nil
 }
+
+// sqlAggregation decide si los periodos pueden agruparse en la base de datos:
+// el repositorio debe soportarlo, la estrategia corresponder a una unidad de
+// calendario en UTC, el reducer tener equivalente en SQL y ningún medidor
+// necesitar sus lecturas crudas. Las series derivadas se calculan sobre los
+// totales, por lo que con ellas solo se admite el reducer sum.
+func (service *ConsumptionService) sqlAggregation(strategy aggregate.AggregationStrategy, reducerName string, metrics []string, loc *time.Location, meters []model.Meter) (repository.AggregatingRepositoryInterface, string, string, bool) {
+	aggregating, supported := service.repository.(repository.AggregatingRepositoryInterface)
+	if !supported || loc != time.UTC {
+		return nil, "", "", false
+	}
+
+	calendarUnit, isCalendar := strategy.(aggregate.CalendarUnit)
+	if !isCalendar {
+		return nil, "", "", false
+	}
+	unit, exists := calendarUnit.Unit()
+	if !exists {
+		return nil, "", "", false
+	}
+
+	function, exists := sqlFunctions[reducerName]
+	if !exists {
+		return nil, "", "", false
+	}
+	if reducerName != defaultReducer {
+		for _, metric := range metrics {
+			if !rawMetrics[metric] {
+				return nil, "", "", false
+			}
+		}
+	}
+
+	for _, meter := range meters {
+		if needsRawReadings(meter) {
+			return nil, "", "", false
+		}
+	}
+
+	return aggregating, unit, function, true
+}
+
+// cumulativeBaselines carga la última lectura anterior a from de cada medidor
+// acumulado, para que el primer periodo incluya el consumo desde esa lectura.
+func (service *ConsumptionService) cumulativeBaselines(ctx context.Context, meters []model.Meter, from time.Time, fields []string) ([]model.Consumption, error) {
+	var meterIDs []int
+	for _, meter := range meters {
+		if meter.Cumulative {
+			meterIDs = append(meterIDs, meter.ID)
+		}
+	}
+	if len(meterIDs) == 0 {
+		return nil, nil
+	}
+	return service.repository.GetLastReadingsBefore(ctx, meterIDs, from, fields)
+}
+
+// meterReadings convierte las lecturas del medidor en consumos por intervalo:
+// calcula las diferencias si el medidor es acumulado y aplica su multiplicador.
+// En los medidores acumulados la primera lectura es la anterior al rango, que
+// sólo sirve de referencia.
+func meterReadings(meter model.Meter, consumptions []model.Consumption) []model.Consumption {
+	if meter.Cumulative {
+		consumptions = aggregate.IntervalDeltas(consumptions)
+	}
+	if multiplier := meterMultiplier(meter); multiplier != 1 {
+		consumptions = aggregate.Scale(consumptions, multiplier)
+	}
+	return consumptions
+}
+
+// needsRawReadings indica si las lecturas del medidor deben transformarse antes
+// de agregarse, lo que impide agruparlas en la base de datos.
+func needsRawReadings(meter model.Meter) bool {
+	return meter.Cumulative || meterMultiplier(meter) != 1
+}
+
+// meterMultiplier devuelve el multiplicador del medidor; cero equivale a 1.
+func meterMultiplier(meter model.Meter) float64 {
+	if meter.Multiplier == 0 {
+		return 1
+	}
+	return meter.Multiplier
+}
+
+// runBounded ejecuta fn para cada índice entre 0 y n-1 con como máximo limit
+// ejecuciones simultáneas. Deja de repartir trabajo si se cancela ctx.
+func runBounded(ctx context.Context, n, limit int, fn func(i int)) {
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < limit; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
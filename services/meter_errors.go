@@ -0,0 +1,81 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Códigos de los errores por medidor.
+const (
+	ErrCodeConsumptionUnavailable = "consumption_unavailable"
+	ErrCodeAddressUnavailable     = "address_unavailable"
+	ErrCodeMeterNotFound          = "meter_not_found"
+)
+
+// meterErrorReasons es el motivo que se devuelve al cliente para cada código.
+// El error de fondo se registra en el log y no se expone en la respuesta.
+var meterErrorReasons = map[string]string{
+	ErrCodeConsumptionUnavailable: "consumption data is temporarily unavailable",
+	ErrCodeAddressUnavailable:     "address is temporarily unavailable",
+	ErrCodeMeterNotFound:          "meter not found",
+}
+
+// MeterError describe por qué no se pudo obtener el consumo de un medidor.
+type MeterError struct {
+	MeterID int    `json:"meter_id"`
+	Code    string `json:"code"`
+	Reason  string `json:"reason"`
+}
+
+// newMeterError registra el error de fondo y devuelve el error del medidor
+// con el motivo fijo de su código.
+func newMeterError(meterID int, code string, err error) *MeterError {
+	log.Printf("meter %d: %s: %v", meterID, code, err)
+	return &MeterError{MeterID: meterID, Code: code, Reason: meterErrorReasons[code]}
+}
+
+// PartialFailureError se devuelve en modo estricto cuando falla algún medidor.
+type PartialFailureError struct {
+	Errors []MeterError
+}
+
+func (e *PartialFailureError) Error() string {
+	meterIDs := make([]string, len(e.Errors))
+	for i, meterError := range e.Errors {
+		meterIDs[i] = fmt.Sprintf("%d (%s)", meterError.MeterID, meterError.Code)
+	}
+	return fmt.Sprintf("failed to get consumption for meters: %s", strings.Join(meterIDs, ", "))
+}
+
+// HasCode indica si algún medidor falló con el código dado.
+func (e *PartialFailureError) HasCode(code string) bool {
+	for _, meterError := range e.Errors {
+		if meterError.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// MeterNotFoundError se devuelve cuando la consulta incluye medidores que no existen.
+type MeterNotFoundError struct {
+	MeterIDs []int
+}
+
+func (e *MeterNotFoundError) Error() string {
+	meterIDs := make([]string, len(e.MeterIDs))
+	for i, meterID := range e.MeterIDs {
+		meterIDs[i] = fmt.Sprint(meterID)
+	}
+	return fmt.Sprintf("meters not found: %s", strings.Join(meterIDs, ", "))
+}
+
+// Errors describe cada medidor desconocido con el código meter_not_found.
+func (e *MeterNotFoundError) Errors() []MeterError {
+	meterErrors := make([]MeterError, len(e.MeterIDs))
+	for i, meterID := range e.MeterIDs {
+		meterErrors[i] = MeterError{MeterID: meterID, Code: ErrCodeMeterNotFound, Reason: meterErrorReasons[ErrCodeMeterNotFound]}
+	}
+	return meterErrors
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+)
+
+// ErrInvalidMeter envuelve los errores de validación de un medidor.
+var ErrInvalidMeter = errors.New("invalid meter")
+
+var meterTypes = map[string]bool{
+	model.MeterTypeConsumer: true,
+	model.MeterTypeProsumer: true,
+}
+
+var meterStatuses = map[string]bool{
+	model.MeterStatusActive:   true,
+	model.MeterStatusInactive: true,
+	model.MeterStatusRemoved:  true,
+}
+
+type MeterService struct {
+	repository repository.MeterRepositoryInterface
+}
+
+func NewMeterService(repository repository.MeterRepositoryInterface) *MeterService {
+	return &MeterService{repository: repository}
+}
+
+func (service *MeterService) ListMeters(ctx context.Context) ([]model.Meter, error) {
+	meters, err := service.repository.ListMeters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if meters == nil {
+		meters = []model.Meter{}
+	}
+	return meters, nil
+}
+
+func (service *MeterService) GetMeter(ctx context.Context, meterID int) (*model.Meter, error) {
+	return service.repository.GetMeter(ctx, meterID)
+}
+
+// CreateMeter valida el medidor, completa los valores por defecto y lo guarda.
+// El ID es obligatorio porque debe coincidir con el meter_id de las lecturas;
+// además los medidores registrados con ID explícito no avanzan la secuencia de
+// Postgres, que podría repetir uno existente.
+func (service *MeterService) CreateMeter(ctx context.Context, meter model.Meter) (*model.Meter, error) {
+	if meter.ID < 1 {
+		return nil, fmt.Errorf("%w: id is required", ErrInvalidMeter)
+	}
+	if err := normalizeMeter(&meter); err != nil {
+		return nil, err
+	}
+	if err := service.repository.CreateMeter(ctx, &meter); err != nil {
+		return nil, err
+	}
+	return &meter, nil
+}
+
+// UpdateMeter reemplaza los datos del medidor meterID.
+func (service *MeterService) UpdateMeter(ctx context.Context, meterID int, meter model.Meter) (*model.Meter, error) {
+	meter.ID = meterID
+	if err := normalizeMeter(&meter); err != nil {
+		return nil, err
+	}
+	if err := service.repository.UpdateMeter(ctx, &meter); err != nil {
+		return nil, err
+	}
+	return &meter, nil
+}
+
+func (service *MeterService) DeleteMeter(ctx context.Context, meterID int) error {
+	return service.repository.DeleteMeter(ctx, meterID)
+}
+
+// normalizeMeter completa los valores por defecto (consumer, multiplicador 1,
+// UTC, active) y valida el resto de los campos.
+func normalizeMeter(meter *model.Meter) error {
+	if meter.Serial == "" {
+		return fmt.Errorf("%w: serial is required", ErrInvalidMeter)
+	}
+
+	if meter.Type == "" {
+		meter.Type = model.MeterTypeConsumer
+	}
+	if !meterTypes[meter.Type] {
+		return fmt.Errorf("%w: invalid type: %s", ErrInvalidMeter, meter.Type)
+	}
+
+	if meter.Multiplier == 0 {
+		meter.Multiplier = 1
+	}
+	if meter.Multiplier < 0 {
+		return fmt.Errorf("%w: invalid multiplier: %g", ErrInvalidMeter, meter.Multiplier)
+	}
+
+	if meter.Timezone == "" {
+		meter.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(meter.Timezone); err != nil {
+		return fmt.Errorf("%w: invalid timezone: %s", ErrInvalidMeter, meter.Timezone)
+	}
+
+	if meter.Status == "" {
+		meter.Status = model.MeterStatusActive
+	}
+	if !meterStatuses[meter.Status] {
+		return fmt.Errorf("%w: invalid status: %s", ErrInvalidMeter, meter.Status)
+	}
+
+	if meter.InstalledAt != nil && meter.RemovedAt != nil && meter.RemovedAt.Before(*meter.InstalledAt) {
+		return fmt.Errorf("%w: removed_at must not be before installed_at", ErrInvalidMeter)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMeterRepository struct {
+	mock.Mock
+}
+
+func (m *MockMeterRepository) GetMeter(ctx context.Context, meterID int) (*model.Meter, error) {
+	args := m.Called(ctx, meterID)
+	return args.Get(0).(*model.Meter), args.Error(1)
+}
+
+func (m *MockMeterRepository) GetMetersByIDs(ctx context.Context, meterIDs []int) ([]model.Meter, error) {
+	args := m.Called(ctx, meterIDs)
+	return args.Get(0).([]model.Meter), args.Error(1)
+}
+
+func (m *MockMeterRepository) ListMeters(ctx context.Context) ([]model.Meter, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.Meter), args.Error(1)
+}
+
+func (m *MockMeterRepository) CreateMeter(ctx context.Context, meter *model.Meter) error {
+	args := m.Called(ctx, meter)
+	return args.Error(0)
+}
+
+func (m *MockMeterRepository) UpdateMeter(ctx context.Context, meter *model.Meter) error {
+	args := m.Called(ctx, meter)
+	return args.Error(0)
+}
+
+func (m *MockMeterRepository) DeleteMeter(ctx context.Context, meterID int) error {
+	args := m.Called(ctx, meterID)
+	return args.Error(0)
+}
+
+var _ repository.MeterRepositoryInterface = (*MockMeterRepository)(nil)
+
+func TestMeterService_CreateMeter(t *testing.T) {
+	installed := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	removed := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		meter         model.Meter
+		repositoryErr error
+		expectedMeter *model.Meter
+		expectedError error
+	}{
+		{
+			name:  "Defaults are filled in",
+			meter: model.Meter{ID: 7, Serial: "SN-7"},
+			expectedMeter: &model.Meter{
+				ID:         7,
+				Serial:     "SN-7",
+				Type:       model.MeterTypeConsumer,
+				Multiplier: 1,
+				Timezone:   "UTC",
+				Status:     model.MeterStatusActive,
+			},
+		},
+		{
+			name:  "Explicit values are kept",
+			meter: model.Meter{ID: 8, Serial: "SN-8", Type: model.MeterTypeProsumer, Cumulative: true, Multiplier: 40, Timezone: "America/Bogota", Tariff: "T2", Status: model.MeterStatusInactive},
+			expectedMeter: &model.Meter{
+				ID:         8,
+				Serial:     "SN-8",
+				Type:       model.MeterTypeProsumer,
+				Cumulative: true,
+				Multiplier: 40,
+				Timezone:   "America/Bogota",
+				Tariff:     "T2",
+				Status:     model.MeterStatusInactive,
+			},
+		},
+		{
+			name:          "ID is required",
+			meter:         model.Meter{Serial: "SN-9"},
+			expectedError: errors.New("invalid meter: id is required"),
+		},
+		{
+			name:          "Serial is required",
+			meter:         model.Meter{ID: 9},
+			expectedError: errors.New("invalid meter: serial is required"),
+		},
+		{
+			name:          "Invalid type",
+			meter:         model.Meter{ID: 9, Serial: "SN-9", Type: "generator"},
+			expectedError: errors.New("invalid meter: invalid type: generator"),
+		},
+		{
+			name:          "Negative multiplier",
+			meter:         model.Meter{ID: 9, Serial: "SN-9", Multiplier: -1},
+			expectedError: errors.New("invalid meter: invalid multiplier: -1"),
+		},
+		{
+			name:          "Invalid timezone",
+			meter:         model.Meter{ID: 9, Serial: "SN-9", Timezone: "Mars/Olympus"},
+			expectedError: errors.New("invalid meter: invalid timezone: Mars/Olympus"),
+		},
+		{
+			name:          "Invalid status",
+			meter:         model.Meter{ID: 9, Serial: "SN-9", Status: "broken"},
+			expectedError: errors.New("invalid meter: invalid status: broken"),
+		},
+		{
+			name:          "Removal before installation",
+			meter:         model.Meter{ID: 9, Serial: "SN-9", InstalledAt: &installed, RemovedAt: &removed},
+			expectedError: errors.New("invalid meter: removed_at must not be before installed_at"),
+		},
+		{
+			name:          "Duplicated serial",
+			meter:         model.Meter{ID: 9, Serial: "SN-7"},
+			repositoryErr: repository.ErrMeterExists,
+			expectedError: repository.ErrMeterExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoMock := new(MockMeterRepository)
+			repoMock.On("CreateMeter", mock.Anything, mock.Anything).Return(tt.repositoryErr).Maybe()
+
+			meter, err := NewMeterService(repoMock).CreateMeter(context.Background(), tt.meter)
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, meter)
+				if tt.repositoryErr == nil {
+					assert.ErrorIs(t, err, ErrInvalidMeter)
+					repoMock.AssertNotCalled(t, "CreateMeter", mock.Anything, mock.Anything)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedMeter, meter)
+		})
+	}
+}
+
+func TestMeterService_UpdateMeter(t *testing.T) {
+	repoMock := new(MockMeterRepository)
+	repoMock.On("UpdateMeter", mock.Anything, &model.Meter{ID: 3, Serial: "SN-3", Type: model.MeterTypeConsumer, Multiplier: 1, Timezone: "UTC", Status: model.MeterStatusRemoved}).Return(nil)
+	repoMock.On("UpdateMeter", mock.Anything, mock.MatchedBy(func(meter *model.Meter) bool { return meter.ID == 4 })).Return(repository.ErrMeterNotFound)
+	service := NewMeterService(repoMock)
+
+	meter, err := service.UpdateMeter(context.Background(), 3, model.Meter{ID: 99, Serial: "SN-3", Status: model.MeterStatusRemoved})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, meter.ID)
+
+	_, err = service.UpdateMeter(context.Background(), 4, model.Meter{Serial: "SN-4"})
+	assert.ErrorIs(t, err, repository.ErrMeterNotFound)
+	repoMock.AssertExpectations(t)
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/importer"
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+)
+
+// Límites de lecturas por página.
+const (
+	DefaultReadingsLimit = 100
+	MaxReadingsLimit     = 1000
+)
+
+// MaxSaveReadings limita las lecturas que se guardan por solicitud.
+const MaxSaveReadings = 10000
+
+// ErrInvalidReadings envuelve los errores de validación de las lecturas a guardar.
+var ErrInvalidReadings = errors.New("invalid readings")
+
+// ReadingError describe por qué se rechazó una lectura; Index es su posición
+// en la solicitud.
+type ReadingError struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// InvalidReadingsError se devuelve cuando alguna lectura no es válida; en ese
+// caso no se guarda ninguna.
+type InvalidReadingsError struct {
+	Errors []ReadingError
+}
+
+func (e *InvalidReadingsError) Error() string {
+	return fmt.Sprintf("%s: %d rejected", ErrInvalidReadings, len(e.Errors))
+}
+
+func (e *InvalidReadingsError) Unwrap() error {
+	return ErrInvalidReadings
+}
+
+type ReadingService struct {
+	repository repository.ReadingRepositoryInterface
+	meters     repository.MeterRepositoryInterface
+	now        func() time.Time
+}
+
+func NewReadingService(repository repository.ReadingRepositoryInterface, meters repository.MeterRepositoryInterface) *ReadingService {
+	return &ReadingService{
+		repository: repository,
+		meters:     meters,
+		now:        time.Now,
+	}
+}
+
+// ReadingQuery agrupa los filtros de una consulta de lecturas crudas.
+type ReadingQuery struct {
+	MeterID int
+	// StartDate, EndDate y Range aceptan los mismos formatos que en
+	// ConsumptionQuery; sin ellos no se limita el rango.
+	StartDate string
+	EndDate   string
+	Range     string
+	// Cursor es el next_cursor de la página anterior.
+	Cursor string
+	// Limit es el máximo de lecturas de la página (DefaultReadingsLimit si es cero).
+	Limit int
+}
+
+// ReadingPage es una página de lecturas. NextCursor está vacío en la última.
+type ReadingPage struct {
+	Readings   []model.Consumption `json:"readings"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+func (service *ReadingService) GetReadings(ctx context.Context, query ReadingQuery) (*ReadingPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = DefaultReadingsLimit
+	}
+	if limit < 0 || limit > MaxReadingsLimit {
+		return nil, fmt.Errorf("invalid limit: %d", query.Limit)
+	}
+
+	start, end, err := readingRange(query, service.now())
+	if err != nil {
+		return nil, err
+	}
+
+	var after *repository.ReadingCursor
+	if query.Cursor != "" {
+		after, err = decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Se pide una lectura de más para saber si hay otra página.
+	readings, err := service.repository.GetReadings(ctx, query.MeterID, start, end, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ReadingPage{Readings: readings}
+	if len(readings) > limit {
+		page.Readings = readings[:limit]
+		last := page.Readings[limit-1]
+		page.NextCursor = encodeCursor(repository.ReadingCursor{Date: last.Date, ID: last.ID})
+	}
+	if page.Readings == nil {
+		page.Readings = []model.Consumption{}
+	}
+	return page, nil
+}
+
+// SaveReadings valida las lecturas y las guarda todas o ninguna. Las fechas se
+// guardan en UTC y los medidores deben estar registrados. Las lecturas de un
+// medidor y fecha ya guardados con otro ID se omiten, reemplazan o rechazan
+// según la política de duplicados del repositorio; el resultado cuenta cada caso.
+func (service *ReadingService) SaveReadings(ctx context.Context, readings []model.Consumption) (*repository.SaveResult, error) {
+	if len(readings) == 0 {
+		return nil, fmt.Errorf("%w: no readings to save", ErrInvalidReadings)
+	}
+	if len(readings) > MaxSaveReadings {
+		return nil, fmt.Errorf("%w: at most %d readings per request, got %d", ErrInvalidReadings, MaxSaveReadings, len(readings))
+	}
+
+	var readingErrors []ReadingError
+	seen := make(map[string]bool, len(readings))
+	meters := make(map[int]bool)
+	var meterIDs []int
+	for i := range readings {
+		reading := &readings[i]
+		reading.Date = reading.Date.UTC()
+		if err := importer.ValidateConsumption(*reading); err != nil {
+			readingErrors = append(readingErrors, ReadingError{Index: i, ID: reading.ID, Reason: err.Error()})
+			continue
+		}
+		if seen[reading.ID] {
+			readingErrors = append(readingErrors, ReadingError{Index: i, ID: reading.ID, Reason: "duplicate id in request"})
+			continue
+		}
+		seen[reading.ID] = true
+		if !meters[reading.MeterID] {
+			meters[reading.MeterID] = true
+			meterIDs = append(meterIDs, reading.MeterID)
+		}
+	}
+	if len(readingErrors) > 0 {
+		return nil, &InvalidReadingsError{Errors: readingErrors}
+	}
+
+	sort.Ints(meterIDs)
+	registered, err := service.meters.GetMetersByIDs(ctx, meterIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(registered) < len(meterIDs) {
+		known := make(map[int]bool, len(registered))
+		for _, meter := range registered {
+			known[meter.ID] = true
+		}
+		var missing []int
+		for _, meterID := range meterIDs {
+			if !known[meterID] {
+				missing = append(missing, meterID)
+			}
+		}
+		return nil, &MeterNotFoundError{MeterIDs: missing}
+	}
+
+	result, err := service.repository.SaveReadings(ctx, readings)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Rejects) > 0 {
+		readingErrors = make([]ReadingError, len(result.Rejects))
+		for i, reject := range result.Rejects {
+			readingErrors[i] = ReadingError{Index: reject.Index, ID: readings[reject.Index].ID, Reason: reject.Reason}
+		}
+		sort.Slice(readingErrors, func(i, j int) bool { return readingErrors[i].Index < readingErrors[j].Index })
+		return nil, &InvalidReadingsError{Errors: readingErrors}
+	}
+	return result, nil
+}
+
+// readingRange resuelve el rango en UTC; cada límite es opcional si no se usa Range.
+func readingRange(query ReadingQuery, now time.Time) (time.Time, time.Time, error) {
+	if query.Range != "" {
+		return relativeRange(query.Range, now.UTC(), time.Monday)
+	}
+
+	var start, end time.Time
+	var err error
+	if query.StartDate != "" {
+		start, err = parseBound(query.StartDate, time.UTC, false)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start_date: %s", query.StartDate)
+		}
+	}
+	if query.EndDate != "" {
+		end, err = parseBound(query.EndDate, time.UTC, true)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end_date: %s", query.EndDate)
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_date must be before end_date")
+	}
+	return start, end, nil
+}
+
+// encodeCursor serializa el cursor como "fecha RFC3339Nano|id" en base64 URL.
+func encodeCursor(cursor repository.ReadingCursor) string {
+	raw := cursor.Date.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (*repository.ReadingCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", value)
+	}
+	dateStr, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, fmt.Errorf("invalid cursor: %s", value)
+	}
+	date, err := time.Parse(time.RFC3339Nano, dateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", value)
+	}
+	return &repository.ReadingCursor{Date: date, ID: id}, nil
+}
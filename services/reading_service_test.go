@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SaidHernandez/bia-comsumtion/business/model"
+	"github.com/SaidHernandez/bia-comsumtion/business/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReadingRepository struct {
+	mock.Mock
+}
+
+func (m *MockReadingRepository) GetReadings(ctx context.Context, meterID int, start, end time.Time, after *repository.ReadingCursor, limit int) ([]model.Consumption, error) {
+	args := m.Called(ctx, meterID, start, end, after, limit)
+	return args.Get(0).([]model.Consumption), args.Error(1)
+}
+
+func (m *MockReadingRepository) SaveReadings(ctx context.Context, readings []model.Consumption) (*repository.SaveResult, error) {
+	args := m.Called(ctx, readings)
+	result, _ := args.Get(0).(*repository.SaveResult)
+	return result, args.Error(1)
+}
+
+func (m *MockReadingRepository) ImportReadings(ctx context.Context, readings []model.Consumption) (*repository.SaveResult, error) {
+	args := m.Called(ctx, readings)
+	result, _ := args.Get(0).(*repository.SaveResult)
+	return result, args.Error(1)
+}
+
+var _ repository.ReadingRepositoryInterface = (*MockReadingRepository)(nil)
+
+func TestReadingService_GetReadings(t *testing.T) {
+	date1 := time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)
+	date2 := time.Date(2023, 7, 4, 10, 15, 0, 0, time.UTC)
+	cursor := encodeCursor(repository.ReadingCursor{Date: date1, ID: "b"})
+
+	tests := []struct {
+		name           string
+		query          ReadingQuery
+		mockRepository func(repoMock *MockReadingRepository)
+		expectedPage   *ReadingPage
+		expectedError  error
+	}{
+		{
+			name:  "First page has a cursor when there are more readings",
+			query: ReadingQuery{MeterID: 1, StartDate: "2023-07-04", EndDate: "2023-07-04", Limit: 2},
+			mockRepository: func(repoMock *MockReadingRepository) {
+				repoMock.On("GetReadings", mock.Anything, 1, day("2023-07-04", time.UTC), day("2023-07-05", time.UTC), (*repository.ReadingCursor)(nil), 3).Return([]model.Consumption{
+					{ID: "a", MeterID: 1, ActiveEnergy: 1, Date: date1},
+					{ID: "b", MeterID: 1, ActiveEnergy: 2, Date: date1},
+					{ID: "c", MeterID: 1, ActiveEnergy: 3, Date: date2},
+				}, nil)
+			},
+			expectedPage: &ReadingPage{
+				Readings: []model.Consumption{
+					{ID: "a", MeterID: 1, ActiveEnergy: 1, Date: date1},
+					{ID: "b", MeterID: 1, ActiveEnergy: 2, Date: date1},
+				},
+				NextCursor: cursor,
+			},
+		},
+		{
+			name:  "Cursor continues after the last reading",
+			query: ReadingQuery{MeterID: 1, Cursor: cursor, Limit: 2},
+			mockRepository: func(repoMock *MockReadingRepository) {
+				repoMock.On("GetReadings", mock.Anything, 1, time.Time{}, time.Time{}, &repository.ReadingCursor{Date: date1, ID: "b"}, 3).Return([]model.Consumption{
+					{ID: "c", MeterID: 1, ActiveEnergy: 3, Date: date2},
+				}, nil)
+			},
+			expectedPage: &ReadingPage{
+				Readings: []model.Consumption{
+					{ID: "c", MeterID: 1, ActiveEnergy: 3, Date: date2},
+				},
+			},
+		},
+		{
+			name:  "Empty page",
+			query: ReadingQuery{MeterID: 1},
+			mockRepository: func(repoMock *MockReadingRepository) {
+				repoMock.On("GetReadings", mock.Anything, 1, time.Time{}, time.Time{}, (*repository.ReadingCursor)(nil), DefaultReadingsLimit+1).Return([]model.Consumption(nil), nil)
+			},
+			expectedPage: &ReadingPage{Readings: []model.Consumption{}},
+		},
+		{
+			name:           "Invalid cursor",
+			query:          ReadingQuery{MeterID: 1, Cursor: "not-a-cursor"},
+			mockRepository: func(repoMock *MockReadingRepository) {},
+			expectedError:  errors.New("invalid cursor: not-a-cursor"),
+		},
+		{
+			name:           "Limit above maximum",
+			query:          ReadingQuery{MeterID: 1, Limit: MaxReadingsLimit + 1},
+			mockRepository: func(repoMock *MockReadingRepository) {},
+			expectedError:  errors.New("invalid limit: 1001"),
+		},
+		{
+			name:           "Invalid end date",
+			query:          ReadingQuery{MeterID: 1, EndDate: "tomorrow"},
+			mockRepository: func(repoMock *MockReadingRepository) {},
+			expectedError:  errors.New("invalid end_date: tomorrow"),
+		},
+		{
+			name:  "Repository error",
+			query: ReadingQuery{MeterID: 1},
+			mockRepository: func(repoMock *MockReadingRepository) {
+				repoMock.On("GetReadings", mock.Anything, 1, time.Time{}, time.Time{}, (*repository.ReadingCursor)(nil), DefaultReadingsLimit+1).Return([]model.Consumption(nil), errors.New("database is locked"))
+			},
+			expectedError: errors.New("database is locked"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoMock := new(MockReadingRepository)
+			tt.mockRepository(repoMock)
+
+			service := NewReadingService(repoMock, new(MockMeterRepository))
+			page, err := service.GetReadings(context.Background(), tt.query)
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, page)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedPage, page)
+			}
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReadingService_SaveReadings(t *testing.T) {
+	bogota, _ := time.LoadLocation("America/Bogota")
+	date := time.Date(2023, 7, 4, 5, 0, 0, 0, bogota)
+	utcDate := time.Date(2023, 7, 4, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		readings       []model.Consumption
+		mockRepository func(repoMock *MockReadingRepository)
+		mockMeters     func(meterMock *MockMeterRepository)
+		expectedResult *repository.SaveResult
+		expectedError  error
+	}{
+		{
+			name: "Saves valid readings in UTC",
+			readings: []model.Consumption{
+				{ID: "a", MeterID: 2, Date: date, ActiveEnergy: 1},
+				{ID: "b", MeterID: 1, Date: date, ActiveEnergy: 2},
+			},
+			mockRepository: func(repoMock *MockReadingRepository) {
+				repoMock.On("SaveReadings", mock.Anything, []model.Consumption{
+					{ID: "a", MeterID: 2, Date: utcDate, ActiveEnergy: 1},
+					{ID: "b", MeterID: 1, Date: utcDate, ActiveEnergy: 2},
+				}).Return(&repository.SaveResult{Inserted: 1, Skipped: 1}, nil)
+			},
+			mockMeters: func(meterMock *MockMeterRepository) {
+				meterMock.On("GetMetersByIDs", mock.Anything, []int{1, 2}).Return([]model.Meter{{ID: 1}, {ID: 2}}, nil)
+			},
+			expectedResult: &repository.SaveResult{Inserted: 1, Skipped: 1},
+		},
+		{
+			name: "Rejects every invalid reading without saving",
+			readings: []model.Consumption{
+				{ID: "a", MeterID: 1, Date: date, ActiveEnergy: -1},
+				{ID: "b", MeterID: 1, Date: date},
+				{ID: "b", MeterID: 1, Date: date},
+				{MeterID: 1, Date: date},
+			},
+			mockRepository: func(repoMock *MockReadingRepository) {},
+			mockMeters:     func(meterMock *MockMeterRepository) {},
+			expectedError: &InvalidReadingsError{Errors: []ReadingError{
+				{Index: 0, ID: "a", Reason: "invalid active_energy: -1"},
+				{Index: 2, ID: "b", Reason: "duplicate id in request"},
+				{Index: 3, Reason: "id is required"},
+			}},
+		},
+		{
+			name: "Duplicates rejected by the policy",
+			readings: []model.Consumption{
+				{ID: "a", MeterID: 1, Date: utcDate},
+				{ID: "b", MeterID: 1, Date: utcDate},
+				{ID: "c", MeterID: 1, Date: utcDate.Add(time.Hour)},
+			},
+			mockRepository: func(repoMock *MockReadingRepository) {
+				repoMock.On("SaveReadings", mock.Anything, mock.Anything).Return(&repository.SaveResult{Rejects: []repository.ReadingReject{
+					{Index: 2, Reason: "duplicate reading for meter 1 at 2023-07-04T11:00:00Z (id stored)"},
+					{Index: 1, Reason: "duplicate reading for meter 1 at 2023-07-04T10:00:00Z (id a)"},
+				}}, nil)
+			},
+			mockMeters: func(meterMock *MockMeterRepository) {
+				meterMock.On("GetMetersByIDs", mock.Anything, []int{1}).Return([]model.Meter{{ID: 1}}, nil)
+			},
+			expectedError: &InvalidReadingsError{Errors: []ReadingError{
+				{Index: 1, ID: "b", Reason: "duplicate reading for meter 1 at 2023-07-04T10:00:00Z (id a)"},
+				{Index: 2, ID: "c", Reason: "duplicate reading for meter 1 at 2023-07-04T11:00:00Z (id stored)"},
+			}},
+		},
+		{
+			name:           "Unknown meters",
+			readings:       []model.Consumption{{ID: "a", MeterID: 3, Date: date}, {ID: "b", MeterID: 1, Date: date}},
+			mockRepository: func(repoMock *MockReadingRepository) {},
+			mockMeters: func(meterMock *MockMeterRepository) {
+				meterMock.On("GetMetersByIDs", mock.Anything, []int{1, 3}).Return([]model.Meter{{ID: 1}}, nil)
+			},
+			expectedError: &MeterNotFoundError{MeterIDs: []int{3}},
+		},
+		{
+			name:           "Empty request",
+			mockRepository: func(repoMock *MockReadingRepository) {},
+			mockMeters:     func(meterMock *MockMeterRepository) {},
+			expectedError:  fmt.Errorf("%w: no readings to save", ErrInvalidReadings),
+		},
+		{
+			name:     "Repository error",
+			readings: []model.Consumption{{ID: "a", MeterID: 1, Date: utcDate}},
+			mockRepository: func(repoMock *MockReadingRepository) {
+				repoMock.On("SaveReadings", mock.Anything, []model.Consumption{{ID: "a", MeterID: 1, Date: utcDate}}).Return(nil, errors.New("database is locked"))
+			},
+			mockMeters: func(meterMock *MockMeterRepository) {
+				meterMock.On("GetMetersByIDs", mock.Anything, []int{1}).Return([]model.Meter{{ID: 1}}, nil)
+			},
+			expectedError: errors.New("database is locked"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoMock := new(MockReadingRepository)
+			tt.mockRepository(repoMock)
+			meterMock := new(MockMeterRepository)
+			tt.mockMeters(meterMock)
+
+			service := NewReadingService(repoMock, meterMock)
+			result, err := service.SaveReadings(context.Background(), tt.readings)
+
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, result)
+			}
+			repoMock.AssertExpectations(t)
+			meterMock.AssertExpectations(t)
+		})
+	}
+}
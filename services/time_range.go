@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// rollingRange reconoce rangos móviles como last_24h, last_7d, last_4w o last_3m.
+var rollingRange = regexp.MustCompile(`^last_([1-9][0-9]*)([hdwm])$`)
+
+// calendarRanges resuelve los rangos relativos al periodo de calendario actual.
+// Cada función recibe la hora actual en la zona de la consulta y devuelve el
+// inicio del periodo y el inicio del siguiente.
+var calendarRanges = map[string]func(now time.Time, weekStart time.Weekday) (time.Time, time.Time){
+	"today": func(now time.Time, _ time.Weekday) (time.Time, time.Time) {
+		start := startOfDay(now)
+		return start, start.AddDate(0, 0, 1)
+	},
+	"yesterday": func(now time.Time, _ time.Weekday) (time.Time, time.Time) {
+		end := startOfDay(now)
+		return end.AddDate(0, 0, -1), end
+	},
+	"this_week": func(now time.Time, weekStart time.Weekday) (time.Time, time.Time) {
+		start := startOfWeek(now, weekStart)
+		return start, start.AddDate(0, 0, 7)
+	},
+	"previous_week": func(now time.Time, weekStart time.Weekday) (time.Time, time.Time) {
+		end := startOfWeek(now, weekStart)
+		return end.AddDate(0, 0, -7), end
+	},
+	"this_month": func(now time.Time, _ time.Weekday) (time.Time, time.Time) {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	},
+	"previous_month": func(now time.Time, _ time.Weekday) (time.Time, time.Time) {
+		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return end.AddDate(0, -1, 0), end
+	},
+	"this_year": func(now time.Time, _ time.Weekday) (time.Time, time.Time) {
+		start := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(1, 0, 0)
+	},
+	"previous_year": func(now time.Time, _ time.Weekday) (time.Time, time.Time) {
+		end := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+		return end.AddDate(-1, 0, 0), end
+	},
+}
+
+// resolveTimeRange devuelve el rango [from, until) de la consulta en la zona loc.
+// Si la consulta trae Range se resuelve respecto a now; si no, start_date y
+// end_date aceptan fechas YYYY-MM-DD (end_date inclusivo hasta el final del día)
+// o instantes RFC3339 (end_date exclusivo).
+func resolveTimeRange(query ConsumptionQuery, loc *time.Location, weekStart time.Weekday, now time.Time) (time.Time, time.Time, error) {
+	if query.Range != "" {
+		return relativeRange(query.Range, now.In(loc), weekStart)
+	}
+
+	from, err := parseBound(query.StartDate, loc, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start_date: %s", query.StartDate)
+	}
+	until, err := parseBound(query.EndDate, loc, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end_date: %s", query.EndDate)
+	}
+	if !from.Before(until) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_date must be before end_date")
+	}
+	return from, until, nil
+}
+
+// relativeRange resuelve un rango relativo. Los rangos móviles terminan en now;
+// los de calendario cubren el periodo completo.
+func relativeRange(expression string, now time.Time, weekStart time.Weekday) (time.Time, time.Time, error) {
+	if calendarRange, exists := calendarRanges[expression]; exists {
+		from, until := calendarRange(now, weekStart)
+		return from, until, nil
+	}
+
+	match := rollingRange.FindStringSubmatch(expression)
+	if match == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range: %s", expression)
+	}
+	amount, err := strconv.Atoi(match[1])
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range: %s", expression)
+	}
+
+	switch match[2] {
+	case "h":
+		return now.Add(-time.Duration(amount) * time.Hour), now, nil
+	case "d":
+		return now.AddDate(0, 0, -amount), now, nil
+	case "w":
+		return now.AddDate(0, 0, -7*amount), now, nil
+	default:
+		return now.AddDate(0, -amount, 0), now, nil
+	}
+}
+
+// parseBound interpreta un límite del rango. Una fecha sin hora como límite
+// final incluye el día completo.
+func parseBound(value string, loc *time.Location, inclusiveDay bool) (time.Time, error) {
+	if date, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		if inclusiveDay {
+			return date.AddDate(0, 0, 1), nil
+		}
+		return date, nil
+	}
+
+	instant, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return instant.In(loc), nil
+}
+
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+func startOfWeek(date time.Time, weekStart time.Weekday) time.Time {
+	daysIntoWeek := (int(date.Weekday()) - int(weekStart) + 7) % 7
+	return startOfDay(date).AddDate(0, 0, -daysIntoWeek)
+}
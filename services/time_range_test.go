@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTimeRange(t *testing.T) {
+	bogota, _ := time.LoadLocation("America/Bogota")
+	// Jueves 13 de julio de 2023, 15:30 en Bogotá.
+	now := time.Date(2023, 7, 13, 20, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		query         ConsumptionQuery
+		loc           *time.Location
+		weekStart     time.Weekday
+		expectedFrom  time.Time
+		expectedUntil time.Time
+		expectedError error
+	}{
+		{
+			name:          "Dates include the whole end day",
+			query:         ConsumptionQuery{StartDate: "2023-07-01", EndDate: "2023-07-31"},
+			loc:           bogota,
+			expectedFrom:  time.Date(2023, 7, 1, 0, 0, 0, 0, bogota),
+			expectedUntil: time.Date(2023, 8, 1, 0, 0, 0, 0, bogota),
+		},
+		{
+			name:          "RFC3339 timestamps are exact instants",
+			query:         ConsumptionQuery{StartDate: "2023-07-04T06:00:00Z", EndDate: "2023-07-04T18:30:00-05:00"},
+			loc:           bogota,
+			expectedFrom:  time.Date(2023, 7, 4, 1, 0, 0, 0, bogota),
+			expectedUntil: time.Date(2023, 7, 4, 18, 30, 0, 0, bogota),
+		},
+		{
+			name:          "Dates and timestamps can be mixed",
+			query:         ConsumptionQuery{StartDate: "2023-07-04", EndDate: "2023-07-04T12:00:00Z"},
+			loc:           time.UTC,
+			expectedFrom:  time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC),
+			expectedUntil: time.Date(2023, 7, 4, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "Last days end now",
+			query:         ConsumptionQuery{Range: "last_7d"},
+			loc:           bogota,
+			expectedFrom:  time.Date(2023, 7, 6, 15, 30, 0, 0, bogota),
+			expectedUntil: time.Date(2023, 7, 13, 15, 30, 0, 0, bogota),
+		},
+		{
+			name:          "Last hours end now",
+			query:         ConsumptionQuery{Range: "last_24h"},
+			loc:           time.UTC,
+			expectedFrom:  time.Date(2023, 7, 12, 20, 30, 0, 0, time.UTC),
+			expectedUntil: now,
+		},
+		{
+			name:          "This month covers the whole month",
+			query:         ConsumptionQuery{Range: "this_month"},
+			loc:           bogota,
+			expectedFrom:  time.Date(2023, 7, 1, 0, 0, 0, 0, bogota),
+			expectedUntil: time.Date(2023, 8, 1, 0, 0, 0, 0, bogota),
+		},
+		{
+			name:          "Previous month",
+			query:         ConsumptionQuery{Range: "previous_month"},
+			loc:           bogota,
+			expectedFrom:  time.Date(2023, 6, 1, 0, 0, 0, 0, bogota),
+			expectedUntil: time.Date(2023, 7, 1, 0, 0, 0, 0, bogota),
+		},
+		{
+			name:          "This week honors week start",
+			query:         ConsumptionQuery{Range: "this_week"},
+			loc:           bogota,
+			weekStart:     time.Sunday,
+			expectedFrom:  time.Date(2023, 7, 9, 0, 0, 0, 0, bogota),
+			expectedUntil: time.Date(2023, 7, 16, 0, 0, 0, 0, bogota),
+		},
+		{
+			name:          "Range replaces the dates",
+			query:         ConsumptionQuery{Range: "yesterday", StartDate: "2020-01-01", EndDate: "2020-01-31"},
+			loc:           bogota,
+			expectedFrom:  time.Date(2023, 7, 12, 0, 0, 0, 0, bogota),
+			expectedUntil: time.Date(2023, 7, 13, 0, 0, 0, 0, bogota),
+		},
+		{
+			name:          "Unknown range",
+			query:         ConsumptionQuery{Range: "last_0d"},
+			loc:           time.UTC,
+			expectedError: errors.New("invalid range: last_0d"),
+		},
+		{
+			name:          "Invalid start date",
+			query:         ConsumptionQuery{StartDate: "07/01/2023", EndDate: "2023-07-31"},
+			loc:           time.UTC,
+			expectedError: errors.New("invalid start_date: 07/01/2023"),
+		},
+		{
+			name:          "Start after end",
+			query:         ConsumptionQuery{StartDate: "2023-07-31", EndDate: "2023-07-01"},
+			loc:           time.UTC,
+			expectedError: errors.New("start_date must be before end_date"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, until, err := resolveTimeRange(tt.query, tt.loc, tt.weekStart, now)
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.expectedFrom.Equal(from), "from: %s", from)
+			assert.True(t, tt.expectedUntil.Equal(until), "until: %s", until)
+			assert.Equal(t, tt.loc, from.Location())
+		})
+	}
+}